@@ -0,0 +1,147 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/acmestack/gobatis/errors"
+)
+
+const (
+	// DefaultCacheType 默认的缓存类型，使用进程内缓存
+	DefaultCacheType = "default"
+	// EvictionLRU 最近最少使用淘汰策略
+	EvictionLRU = "LRU"
+	// DefaultCacheSize 默认的缓存容量
+	DefaultCacheSize = 1024
+)
+
+// Cache 二级缓存接口，每个namespace使用一个缓存实例，实现需要保证并发安全
+type Cache interface {
+	// Get 获得缓存的值
+	Get(key string) (interface{}, bool)
+	// Put 缓存值
+	Put(key string, value interface{})
+	// Clear 清空缓存，namespace中执行修改语句时调用
+	Clear()
+	// Size 缓存的数量
+	Size() int
+}
+
+// Config mapper文件中<cache>元素的配置
+type Config struct {
+	// Namespace 缓存所属的namespace
+	Namespace string
+	// Type 缓存类型，对应RegisterCache注册的名称，为空时使用DefaultCacheType
+	Type string
+	// Eviction 淘汰策略，为空时使用EvictionLRU
+	Eviction string
+	// Size 缓存容量，小于等于0时使用DefaultCacheSize
+	Size int
+	// FlushInterval 缓存清空间隔，小于等于0时不自动清空
+	FlushInterval time.Duration
+}
+
+// Creator 根据配置创建缓存
+type Creator func(config Config) (Cache, error)
+
+var gCacheCreators = map[string]Creator{
+	DefaultCacheType: createDefaultCache,
+}
+var gCacheLock sync.Mutex
+
+// RegisterCache 注册缓存类型，在<cache type="...">中使用该名称选择缓存实现
+func RegisterCache(cacheType string, creator Creator) {
+	gCacheLock.Lock()
+	defer gCacheLock.Unlock()
+
+	gCacheCreators[cacheType] = creator
+}
+
+// NewCache 根据配置创建缓存，配置了FlushInterval时按间隔自动清空
+func NewCache(config Config) (Cache, error) {
+	if config.Type == "" {
+		config.Type = DefaultCacheType
+	}
+	gCacheLock.Lock()
+	creator, ok := gCacheCreators[config.Type]
+	gCacheLock.Unlock()
+	if !ok {
+		return nil, errors.CacheTypeNotSupport
+	}
+
+	c, err := creator(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.FlushInterval > 0 {
+		c = NewScheduledCache(c, config.FlushInterval)
+	}
+	return c, nil
+}
+
+func createDefaultCache(config Config) (Cache, error) {
+	switch config.Eviction {
+	case "", EvictionLRU:
+		return NewLruCache(config.Size), nil
+	}
+	return nil, errors.CacheConfigError
+}
+
+// ScheduledCache 按间隔清空的缓存，在访问时检查是否需要清空
+type ScheduledCache struct {
+	Cache
+	interval  time.Duration
+	lastClear time.Time
+	lock      sync.Mutex
+}
+
+func NewScheduledCache(c Cache, interval time.Duration) *ScheduledCache {
+	return &ScheduledCache{
+		Cache:     c,
+		interval:  interval,
+		lastClear: time.Now(),
+	}
+}
+
+func (c *ScheduledCache) Get(key string) (interface{}, bool) {
+	c.clearWhenStale()
+	return c.Cache.Get(key)
+}
+
+func (c *ScheduledCache) Put(key string, value interface{}) {
+	c.clearWhenStale()
+	c.Cache.Put(key, value)
+}
+
+func (c *ScheduledCache) Size() int {
+	c.clearWhenStale()
+	return c.Cache.Size()
+}
+
+func (c *ScheduledCache) clearWhenStale() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if time.Since(c.lastClear) >= c.interval {
+		c.Cache.Clear()
+		c.lastClear = time.Now()
+	}
+}
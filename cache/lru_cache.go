@@ -0,0 +1,91 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+// LruCache 进程内的LRU缓存
+type LruCache struct {
+	capacity int
+	list     *list.List
+	items    map[string]*list.Element
+	lock     sync.Mutex
+}
+
+// NewLruCache 创建LRU缓存，capacity小于等于0时使用DefaultCacheSize
+func NewLruCache(capacity int) *LruCache {
+	if capacity <= 0 {
+		capacity = DefaultCacheSize
+	}
+	return &LruCache{
+		capacity: capacity,
+		list:     list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+func (c *LruCache) Get(key string) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.list.MoveToFront(e)
+		return e.Value.(*lruEntry).value, true
+	}
+	return nil, false
+}
+
+func (c *LruCache) Put(key string, value interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		e.Value.(*lruEntry).value = value
+		c.list.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.list.PushFront(&lruEntry{key: key, value: value})
+	if c.list.Len() > c.capacity {
+		e := c.list.Back()
+		c.list.Remove(e)
+		delete(c.items, e.Value.(*lruEntry).key)
+	}
+}
+
+func (c *LruCache) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.list.Init()
+	c.items = map[string]*list.Element{}
+}
+
+func (c *LruCache) Size() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.list.Len()
+}
@@ -68,3 +68,13 @@ func CalcKey(sql string, params map[string]interface{}) MetadataCacheKey {
 	}
 	return MetadataCacheKey(buf.String())
 }
+
+// CalcParamsKey 使用sql语句及顺序参数计算缓存key
+func CalcParamsKey(sql string, params ...interface{}) MetadataCacheKey {
+	buf := strings.Builder{}
+	buf.WriteString(sql)
+	for i := range params {
+		buf.WriteString(fmt.Sprintf("\x00%T:%v", params[i], params[i]))
+	}
+	return MetadataCacheKey(buf.String())
+}
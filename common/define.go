@@ -26,4 +26,8 @@ type IterFunc func(idx int64, bean interface{}) bool
 
 const (
 	ColumnName = "column"
+	// ColumnPrimaryKey column tag中标记主键的选项，如`column:"id,pk"`
+	ColumnPrimaryKey = "pk"
+	// ColumnAutoIncrement column tag中标记自增列的选项，插入时由数据库生成
+	ColumnAutoIncrement = "auto"
 )
@@ -0,0 +1,201 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gobatis
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/parsing/sqlparser"
+	"github.com/acmestack/gobatis/parsing/template"
+	"github.com/acmestack/gobatis/parsing/xml"
+	"github.com/acmestack/gobatis/reflection"
+)
+
+// Configuration 保存注册的sql语句及模型缓存，使用NewSessionManager传入后，
+// 同一进程中的多个SessionManager可以使用不同的mapper（包括相同的语句id）；
+// 包级别的注册函数（RegisterMapperFile、ScanMapperFile、RegisterModel等）使用默认的Configuration
+type Configuration struct {
+	dynamicSqlMgr  *xml.Manager
+	templateSqlMgr *template.Manager
+	objectCache    *ObjectCache
+	//ScanMapperFile扫描过的目录
+	scanDirs []string
+	lock     sync.Mutex
+}
+
+var defaultConfiguration = NewConfiguration()
+
+func NewConfiguration() *Configuration {
+	return &Configuration{
+		dynamicSqlMgr:  xml.NewManager(),
+		templateSqlMgr: template.NewManager(),
+		objectCache:    NewObjectCache(),
+	}
+}
+
+// DefaultConfiguration 获得包级别注册函数使用的默认Configuration
+func DefaultConfiguration() *Configuration {
+	return defaultConfiguration
+}
+
+func (config *Configuration) RegisterSql(sqlId string, sql string) error {
+	return config.dynamicSqlMgr.RegisterSql(sqlId, sql)
+}
+
+func (config *Configuration) UnregisterSql(sqlId string) {
+	config.dynamicSqlMgr.UnregisterSql(sqlId)
+}
+
+func (config *Configuration) RegisterMapperData(data []byte) error {
+	return config.dynamicSqlMgr.RegisterData(data)
+}
+
+func (config *Configuration) RegisterMapperFile(file string) error {
+	return config.dynamicSqlMgr.RegisterFile(mapperPath(file))
+}
+
+// RegisterMapperFileFS 注册fsys中的mapper文件，可以用于embed.FS
+func (config *Configuration) RegisterMapperFileFS(fsys fs.FS, file string) error {
+	return config.dynamicSqlMgr.RegisterFileFS(fsys, file)
+}
+
+func (config *Configuration) FindDynamicSqlParser(sqlId string) (sqlparser.SqlParser, bool) {
+	return config.dynamicSqlMgr.FindSqlParser(sqlId)
+}
+
+func (config *Configuration) RegisterTemplateData(data []byte) error {
+	return config.templateSqlMgr.RegisterData(data)
+}
+
+func (config *Configuration) RegisterTemplateFile(file string) error {
+	return config.templateSqlMgr.RegisterFile(mapperPath(file))
+}
+
+// RegisterTemplateFileFS 注册fsys中的模板文件，可以用于embed.FS
+func (config *Configuration) RegisterTemplateFileFS(fsys fs.FS, file string) error {
+	return config.templateSqlMgr.RegisterFileFS(fsys, file)
+}
+
+func (config *Configuration) FindTemplateSqlParser(sqlId string) (sqlparser.SqlParser, bool) {
+	return config.templateSqlMgr.FindSqlParser(sqlId)
+}
+
+// FindSqlParser 查找语句，优先查找xml mapper中的语句
+func (config *Configuration) FindSqlParser(sqlId string) (sqlparser.SqlParser, bool) {
+	if ret, ok := config.FindDynamicSqlParser(sqlId); ok {
+		return ret, true
+	}
+	return config.FindTemplateSqlParser(sqlId)
+}
+
+// ScanMapperFile 扫描目录中的xml及tpl文件并注册
+func (config *Configuration) ScanMapperFile(dir string) error {
+	config.addScanDir(dir)
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return registerMapper(path, config.RegisterMapperFile, config.RegisterTemplateFile)
+		}
+		return nil
+	})
+}
+
+// ScanMapperFS 扫描fsys中root目录下的xml及tpl文件并注册，可以用于embed.FS
+func (config *Configuration) ScanMapperFS(fsys fs.FS, root string) error {
+	return fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return registerMapper(path, func(file string) error {
+				return config.RegisterMapperFileFS(fsys, file)
+			}, func(file string) error {
+				return config.RegisterTemplateFileFS(fsys, file)
+			})
+		}
+		return nil
+	})
+}
+
+// WatchMapperFile 每隔interval检查dirs中的mapper文件并重新加载，dirs为空时检查ScanMapperFile扫描过的目录
+// interval小于等于0或没有需要检查的目录时返回错误
+func (config *Configuration) WatchMapperFile(interval time.Duration, dirs ...string) (*MapperWatcher, error) {
+	if len(dirs) == 0 {
+		dirs = config.scannedDirs()
+	}
+	if len(dirs) == 0 {
+		return nil, errors.WatchDirNotFound
+	}
+	return newMapperWatcher(config, interval, dirs)
+}
+
+// RegisterModel 注册struct模型
+func (config *Configuration) RegisterModel(model interface{}) error {
+	return config.objectCache.RegisterModelWithName("", model)
+}
+
+func (config *Configuration) RegisterModelWithName(name string, model interface{}) error {
+	return config.objectCache.RegisterModelWithName(name, model)
+}
+
+// ParseObject 使用Configuration的模型缓存解析结果对象
+func (config *Configuration) ParseObject(bean interface{}) (reflection.Object, error) {
+	return config.objectCache.ParseObject(bean)
+}
+
+func (config *Configuration) reloadMapperFile(path string) error {
+	path = mapperPath(path)
+	if filepath.Ext(path) == ".xml" {
+		return config.dynamicSqlMgr.ReloadFile(path)
+	}
+	return config.templateSqlMgr.ReloadFile(path)
+}
+
+// mapperPath 获得mapper文件的绝对路径，注册及重新加载时同一文件的不同写法使用相同的路径
+func mapperPath(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		return abs
+	}
+	return filepath.Clean(file)
+}
+
+func (config *Configuration) addScanDir(dir string) {
+	config.lock.Lock()
+	defer config.lock.Unlock()
+
+	for _, v := range config.scanDirs {
+		if v == dir {
+			return
+		}
+	}
+	config.scanDirs = append(config.scanDirs, dir)
+}
+
+func (config *Configuration) scannedDirs() []string {
+	config.lock.Lock()
+	defer config.lock.Unlock()
+
+	return append([]string(nil), config.scanDirs...)
+}
@@ -35,7 +35,7 @@ func (conn *DefaultConnection) Prepare(sqlStr string) (statement.Statement, erro
 	db := (*sql.DB)(conn)
 	s, err := db.Prepare(sqlStr)
 	if err != nil {
-		return nil, errors.ConnectionPrepareError
+		return nil, err
 	}
 	return (*DefaultStatement)(s), nil
 }
@@ -49,7 +49,8 @@ func (conn *DefaultConnection) Query(ctx context.Context, result reflection.Obje
 	defer rows.Close()
 
 	util.ScanRows(rows, result)
-	return nil
+	//读取中断（如超时）时返回错误
+	return rows.Err()
 }
 
 func (conn *DefaultConnection) Exec(ctx context.Context, sqlStr string, params ...interface{}) (common.Result, error) {
@@ -66,7 +67,8 @@ func (s *DefaultStatement) Query(ctx context.Context, result reflection.Object,
 	defer rows.Close()
 
 	util.ScanRows(rows, result)
-	return nil
+	//读取中断（如超时）时返回错误
+	return rows.Err()
 }
 
 func (s *DefaultStatement) Exec(ctx context.Context, params ...interface{}) (common.Result, error) {
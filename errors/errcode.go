@@ -37,12 +37,17 @@ var (
 	GetObjectInfoFailed         = gobatisError("11121", "Parse interface's info failed")
 	SqlIdDuplicates             = gobatisError("11205", "Sql id is duplicates")
 	DeserializeFailed           = gobatisError("11206", "Deserialize value failed")
+	MapperValidateError         = gobatisError("11207", "Mapper validate failed")
 	ParseSqlVarError            = gobatisError("12001", "SQL PARSE ERROR")
 	ParseSqlParamError          = gobatisError("12002", "SQL PARSE parameter error")
 	ParseSqlParamVarNumberError = gobatisError("12003", "SQL PARSE parameter var number error")
 	ParseParserNilError         = gobatisError("12004", "Dynamic sql parser is nil error")
 	ParseDynamicSqlError        = gobatisError("12010", "Parse dynamic sql error")
 	ParseTemplateNilError       = gobatisError("12101", "Parse template is nil")
+	CacheTypeNotSupport         = gobatisError("12201", "Mapper cache type not support")
+	CacheConfigError            = gobatisError("12202", "Mapper cache config error")
+	WatchIntervalInvalid        = gobatisError("12301", "Mapper watch interval must be positive")
+	WatchDirNotFound            = gobatisError("12302", "Mapper watch dirs not specified and no dir scanned")
 	ExecutorCommitError         = gobatisError("21001", "executor was closed when transaction commit")
 	ExecutorBeginError          = gobatisError("21002", "executor was closed when transaction begin")
 	ExecutorQueryError          = gobatisError("21003", "executor was closed when exec sql")
@@ -50,6 +55,13 @@ var (
 	TransactionWithoutBegin     = gobatisError("22001", "Transaction without begin")
 	TransactionCommitError      = gobatisError("22002", "Transaction commit error")
 	TransactionBusinessError    = gobatisError("22003", "Business error in transaction")
+	TransactionAlreadyBegin     = gobatisError("22004", "Transaction already begin")
+	TransactionNotExist         = gobatisError("22005", "No existing transaction found for propagation mandatory")
+	TransactionExist            = gobatisError("22006", "Existing transaction found for propagation never")
+	TransactionRollbackOnly     = gobatisError("22007", "Transaction rolled back because it has been marked as rollback-only")
+	TransactionNestedNotSupport = gobatisError("22008", "Nested transaction not support")
+	PropagationNotSupport       = gobatisError("22009", "Transaction propagation not support")
+	SavepointNameInvalid        = gobatisError("22010", "Savepoint name invalid")
 	ConnectionPrepareError      = gobatisError("23001", "Connection prepare error")
 	StatementQueryError         = gobatisError("24001", "statement query error")
 	StatementExecError          = gobatisError("24002", "statement exec error")
@@ -61,6 +73,19 @@ var (
 	ResultNameNotFound          = gobatisError("31004", "result name not found")
 	ResultSelectEmptyValue      = gobatisError("31005", "select return empty value")
 	ResultSetValueFailed        = gobatisError("31006", "result set value failed")
+	RunnerIterateNotSupport     = gobatisError("31007", "Runner not support iterate, select only")
+	IterFuncIsNil               = gobatisError("31008", "iterate function is nil")
+	IterateSliceNotSupport      = gobatisError("31009", "iterate bean cannot be a slice")
+	ResultMapTypeNotSupport     = gobatisError("31010", "result map support struct or slice of struct only")
+	SelectKeyEmptyResult        = gobatisError("31011", "selectKey return empty value")
+	MapperNotStructPointer      = gobatisError("31012", "mapper must be a pointer of struct")
+	MapperFuncNotSupport        = gobatisError("31013", "mapper function signature not support")
+	StatementNotFound           = gobatisError("31014", "statement not found")
+	PrimaryKeyNotFound          = gobatisError("31015", "model primary key not found")
+	IterateResultMapNotSupport  = gobatisError("31016", "iterate not support statement with result map")
+	ResultMapPropertyTypeError  = gobatisError("31017", "result map association must be struct and collection must be slice of struct")
+	ResultMapValueColumnError   = gobatisError("31018", "simple type map value requires exactly one column besides the key")
+	ModelColumnsNotEnough       = gobatisError("31019", "model requires non auto increment and non primary key columns")
 )
 
 func gobatisError(code, message string) errCode {
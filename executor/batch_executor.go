@@ -0,0 +1,258 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package executor
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/acmestack/gobatis/common"
+	"github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/reflection"
+	"github.com/acmestack/gobatis/transaction"
+)
+
+const (
+	// DefaultBatchSize 同一语句排队执行的最大数量，超过时自动刷新
+	DefaultBatchSize = 1000
+)
+
+// BatchResult 批量执行同一语句的结果
+type BatchResult struct {
+	// Sql 预编译的sql语句
+	Sql string
+	// Params 每次执行的参数
+	Params [][]interface{}
+	// UpdateCounts 每次执行影响的行数，与Params一一对应
+	UpdateCounts []int64
+}
+
+// queuedResult 排队等待执行的语句结果，影响行数及自增id在刷新后才能获得，此时均返回0
+type queuedResult struct{}
+
+func (r queuedResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (r queuedResult) RowsAffected() (int64, error) {
+	return 0, nil
+}
+
+// BatchExecutor 批量执行器，在事务中将连续的相同sql语句排队，使用同一个预编译语句在查询、提交或
+// 调用FlushStatements时批量执行；不在事务中时语句直接执行
+// 提交时执行的结果保留到下次调用FlushStatements或开启新的事务
+type BatchExecutor struct {
+	transaction transaction.Transaction
+	closed      bool
+	batchSize   int
+	txCache     *StmtCache
+	// txCtx 开启事务时的context，提交时使用该context执行排队的语句
+	txCtx context.Context
+
+	current *BatchResult
+	results []BatchResult
+}
+
+// NewBatchExecutor 创建批量执行器，batchSize小于等于0时使用DefaultBatchSize
+func NewBatchExecutor(transaction transaction.Transaction, batchSize int) *BatchExecutor {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &BatchExecutor{transaction: transaction, batchSize: batchSize}
+}
+
+func (exec *BatchExecutor) Close(rollback bool) {
+	defer func() {
+		exec.closeTxCache()
+		if exec.transaction != nil {
+			exec.transaction.Close()
+		}
+		exec.transaction = nil
+		exec.closed = true
+	}()
+
+	if rollback {
+		exec.Rollback(true)
+	}
+}
+
+func (exec *BatchExecutor) Query(ctx context.Context, result reflection.Object, sql string, params ...interface{}) error {
+	if exec.closed {
+		return errors.ExecutorQueryError
+	}
+
+	//查询前执行排队的语句，保证能查询到修改的数据
+	if err := exec.flush(ctx); err != nil {
+		return err
+	}
+
+	conn := exec.transaction.GetConnection()
+	if conn == nil {
+		return errors.ExecutorGetConnectionError
+	}
+
+	return conn.Query(ctx, result, sql, params...)
+}
+
+func (exec *BatchExecutor) Exec(ctx context.Context, sql string, params ...interface{}) (common.Result, error) {
+	if exec.closed {
+		return nil, errors.ExecutorQueryError
+	}
+
+	if exec.txCache == nil {
+		conn := exec.transaction.GetConnection()
+		if conn == nil {
+			return nil, errors.ExecutorGetConnectionError
+		}
+		return conn.Exec(ctx, sql, params...)
+	}
+
+	if exec.current != nil && (exec.current.Sql != sql || len(exec.current.Params) >= exec.batchSize) {
+		if err := exec.flush(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if exec.current == nil {
+		exec.current = &BatchResult{Sql: sql}
+	}
+	exec.current.Params = append(exec.current.Params, params)
+	return queuedResult{}, nil
+}
+
+// FlushStatements 执行所有排队的语句，返回自上次调用以来所有批量执行的结果
+func (exec *BatchExecutor) FlushStatements(ctx context.Context) ([]BatchResult, error) {
+	if exec.closed {
+		return nil, errors.ExecutorQueryError
+	}
+
+	err := exec.flush(ctx)
+	ret := exec.results
+	exec.results = nil
+	return ret, err
+}
+
+// BeforeSavepoint 设置保存点前执行排队的语句，结果保留到下次调用FlushStatements
+func (exec *BatchExecutor) BeforeSavepoint() error {
+	if exec.closed {
+		return errors.ExecutorQueryError
+	}
+	return exec.flush(exec.context())
+}
+
+// BeforeRollbackTo 丢弃排队尚未执行的语句，这些语句在保存点之后加入，回滚后不应再执行
+func (exec *BatchExecutor) BeforeRollbackTo() {
+	exec.current = nil
+}
+
+func (exec *BatchExecutor) Begin(ctx context.Context, opts *sql.TxOptions) error {
+	if exec.closed {
+		return errors.ExecutorBeginError
+	}
+
+	err := exec.transaction.Begin(ctx, opts)
+	if err != nil {
+		return err
+	}
+	exec.txCache = NewStmtCache(0)
+	exec.txCtx = ctx
+	exec.results = nil
+	return nil
+}
+
+func (exec *BatchExecutor) Commit(require bool) error {
+	if exec.closed {
+		return errors.ExecutorCommitError
+	}
+
+	if require {
+		if err := exec.flush(exec.context()); err != nil {
+			exec.Rollback(true)
+			return err
+		}
+		exec.closeTxCache()
+		exec.txCtx = nil
+		return exec.transaction.Commit()
+	}
+
+	return nil
+}
+
+func (exec *BatchExecutor) Rollback(require bool) error {
+	if !exec.closed {
+		if require {
+			exec.current = nil
+			exec.results = nil
+			exec.txCtx = nil
+			exec.closeTxCache()
+			return exec.transaction.Rollback()
+		}
+	}
+	return nil
+}
+
+// flush 使用预编译语句执行当前排队的语句，执行失败时结果中只包含已执行部分
+func (exec *BatchExecutor) flush(ctx context.Context) error {
+	batch := exec.current
+	if batch == nil {
+		return nil
+	}
+	exec.current = nil
+
+	conn := exec.transaction.GetConnection()
+	if conn == nil {
+		return errors.ExecutorGetConnectionError
+	}
+
+	stmt, release, err := exec.txCache.Get(batch.Sql, conn.Prepare)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	batch.UpdateCounts = make([]int64, 0, len(batch.Params))
+	for _, params := range batch.Params {
+		ret, err := stmt.Exec(ctx, params...)
+		if err != nil {
+			exec.results = append(exec.results, *batch)
+			return err
+		}
+		count, err := ret.RowsAffected()
+		if err != nil {
+			exec.results = append(exec.results, *batch)
+			return err
+		}
+		batch.UpdateCounts = append(batch.UpdateCounts, count)
+	}
+	exec.results = append(exec.results, *batch)
+	return nil
+}
+
+// context 返回执行排队语句使用的context，未记录开启事务时的context则使用context.Background()
+func (exec *BatchExecutor) context() context.Context {
+	if exec.txCtx == nil {
+		return context.Background()
+	}
+	return exec.txCtx
+}
+
+func (exec *BatchExecutor) closeTxCache() {
+	if exec.txCache != nil {
+		exec.txCache.Close()
+		exec.txCache = nil
+	}
+}
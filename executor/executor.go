@@ -19,10 +19,24 @@ package executor
 
 import (
 	"context"
+	"database/sql"
+
 	"github.com/acmestack/gobatis/common"
 	"github.com/acmestack/gobatis/reflection"
 )
 
+// Type 执行器类型
+type Type int
+
+const (
+	// TypeSimple 直接执行sql语句
+	TypeSimple Type = iota
+	// TypePrepare 预编译并缓存sql语句后执行
+	TypePrepare
+	// TypeBatch 事务中批量执行相同的sql语句
+	TypeBatch
+)
+
 type Executor interface {
 	Close(rollback bool)
 
@@ -30,9 +44,112 @@ type Executor interface {
 
 	Exec(ctx context.Context, sql string, params ...interface{}) (common.Result, error)
 
-	Begin() error
+	Begin(ctx context.Context, opts *sql.TxOptions) error
 
 	Commit(require bool) error
 
 	Rollback(require bool) error
 }
+
+// BatchFlusher 会将语句排队执行的执行器实现的可选接口，如BatchExecutor
+type BatchFlusher interface {
+	// FlushStatements 执行排队的语句
+	FlushStatements(ctx context.Context) ([]BatchResult, error)
+}
+
+// LocalCacheClearer 带有一级缓存的执行器实现的可选接口，如LocalCacheExecutor
+type LocalCacheClearer interface {
+	// ClearLocalCache 清空一级缓存
+	ClearLocalCache()
+}
+
+// SavepointListener 需要在保存点操作前处理排队语句的执行器实现的可选接口，如BatchExecutor
+type SavepointListener interface {
+	// BeforeSavepoint 设置保存点前调用，执行排队的语句使其位于保存点之前
+	BeforeSavepoint() error
+	// BeforeRollbackTo 回滚到保存点前调用，丢弃保存点之后排队尚未执行的语句
+	BeforeRollbackTo()
+}
+
+// Wrapper 包装其他执行器的执行器实现的可选接口，拦截器包装执行器时实现该接口才能使用被包装执行器的可选接口
+type Wrapper interface {
+	Unwrap() Executor
+}
+
+// FlushStatements 执行器或其包装的执行器实现BatchFlusher时执行排队的语句，否则返回nil
+func FlushStatements(ctx context.Context, e Executor) ([]BatchResult, error) {
+	for e != nil {
+		if f, ok := e.(BatchFlusher); ok {
+			return f.FlushStatements(ctx)
+		}
+		w, ok := e.(Wrapper)
+		if !ok {
+			break
+		}
+		e = w.Unwrap()
+	}
+	return nil, nil
+}
+
+// ClearLocalCache 清空执行器及其包装的执行器中实现LocalCacheClearer的一级缓存
+func ClearLocalCache(e Executor) {
+	for e != nil {
+		if c, ok := e.(LocalCacheClearer); ok {
+			c.ClearLocalCache()
+		}
+		w, ok := e.(Wrapper)
+		if !ok {
+			break
+		}
+		e = w.Unwrap()
+	}
+}
+
+type fetchSizeKey struct{}
+
+// WithFetchSize 将语句的fetchSize保存到context中
+// database/sql没有设置fetchSize的接口，执行器不使用该值，支持的驱动或拦截器可以通过FetchSize获得
+func WithFetchSize(ctx context.Context, size int) context.Context {
+	return context.WithValue(ctx, fetchSizeKey{}, size)
+}
+
+// FetchSize 获得context中语句配置的fetchSize，未设置时返回0
+func FetchSize(ctx context.Context) int {
+	if ctx == nil {
+		return 0
+	}
+	if v, ok := ctx.Value(fetchSizeKey{}).(int); ok {
+		return v
+	}
+	return 0
+}
+
+// BeforeSavepoint 执行器或其包装的执行器实现SavepointListener时在设置保存点前调用
+func BeforeSavepoint(e Executor) error {
+	for e != nil {
+		if l, ok := e.(SavepointListener); ok {
+			return l.BeforeSavepoint()
+		}
+		w, ok := e.(Wrapper)
+		if !ok {
+			break
+		}
+		e = w.Unwrap()
+	}
+	return nil
+}
+
+// BeforeRollbackTo 执行器或其包装的执行器实现SavepointListener时在回滚到保存点前调用
+func BeforeRollbackTo(e Executor) {
+	for e != nil {
+		if l, ok := e.(SavepointListener); ok {
+			l.BeforeRollbackTo()
+			return
+		}
+		w, ok := e.(Wrapper)
+		if !ok {
+			break
+		}
+		e = w.Unwrap()
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/acmestack/gobatis/cache"
+	"github.com/acmestack/gobatis/util"
+)
+
+type wrapExecutor struct {
+	Executor
+}
+
+func (e *wrapExecutor) Unwrap() Executor {
+	return e.Executor
+}
+
+func TestOptionalInterfaces(t *testing.T) {
+	batch := NewBatchExecutor(nil, 0)
+	batch.results = []BatchResult{{Sql: "INSERT"}}
+	local := NewLocalCacheExecutor(batch)
+	local.cache[cache.MetadataCacheKey("k")] = &util.CachedRows{}
+
+	t.Run("flush through wrapper", func(t *testing.T) {
+		results, err := FlushStatements(context.Background(), &wrapExecutor{local})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Sql != "INSERT" {
+			t.Fatalf("expect batch results, get %v", results)
+		}
+	})
+
+	t.Run("clear through wrapper", func(t *testing.T) {
+		ClearLocalCache(&wrapExecutor{local})
+		if len(local.cache) != 0 {
+			t.Fatal("expect local cache cleared")
+		}
+	})
+
+	t.Run("rollback to through wrapper", func(t *testing.T) {
+		batch.current = &BatchResult{Sql: "INSERT"}
+		BeforeRollbackTo(&wrapExecutor{local})
+		if batch.current != nil {
+			t.Fatal("expect queued statements discarded")
+		}
+	})
+
+	t.Run("not implemented", func(t *testing.T) {
+		results, err := FlushStatements(context.Background(), NewSimpleExecutor(nil))
+		if results != nil || err != nil {
+			t.Fatalf("expect nil, get %v %v", results, err)
+		}
+		ClearLocalCache(NewSimpleExecutor(nil))
+		if err := BeforeSavepoint(NewSimpleExecutor(nil)); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
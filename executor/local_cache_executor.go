@@ -0,0 +1,101 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package executor
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/acmestack/gobatis/cache"
+	"github.com/acmestack/gobatis/common"
+	"github.com/acmestack/gobatis/reflection"
+	"github.com/acmestack/gobatis/util"
+)
+
+// LocalCacheExecutor 一级缓存执行器，在session内缓存相同sql及参数的查询结果
+// 执行修改语句、开启、提交、回滚事务及关闭时清空缓存
+type LocalCacheExecutor struct {
+	Executor
+	cache map[cache.MetadataCacheKey]*util.CachedRows
+}
+
+func NewLocalCacheExecutor(e Executor) *LocalCacheExecutor {
+	return &LocalCacheExecutor{
+		Executor: e,
+		cache:    map[cache.MetadataCacheKey]*util.CachedRows{},
+	}
+}
+
+func (exec *LocalCacheExecutor) Close(rollback bool) {
+	exec.ClearLocalCache()
+	exec.Executor.Close(rollback)
+}
+
+// Query 命中缓存时直接使用缓存结果，流式查询不使用缓存
+func (exec *LocalCacheExecutor) Query(ctx context.Context, result reflection.Object, sql string, params ...interface{}) error {
+	if _, ok := result.(*reflection.IterObject); ok {
+		return exec.Executor.Query(ctx, result, sql, params...)
+	}
+
+	key := cache.CalcParamsKey(sql, params...)
+	if rows, ok := exec.cache[key]; ok {
+		rows.Scan(result)
+		return nil
+	}
+
+	recorder := util.NewRowsRecorder()
+	err := exec.Executor.Query(ctx, recorder, sql, params...)
+	if err != nil {
+		return err
+	}
+	exec.cache[key] = recorder.Rows()
+	recorder.Rows().Scan(result)
+	return nil
+}
+
+func (exec *LocalCacheExecutor) Exec(ctx context.Context, sql string, params ...interface{}) (common.Result, error) {
+	exec.ClearLocalCache()
+	return exec.Executor.Exec(ctx, sql, params...)
+}
+
+func (exec *LocalCacheExecutor) Begin(ctx context.Context, opts *sql.TxOptions) error {
+	exec.ClearLocalCache()
+	return exec.Executor.Begin(ctx, opts)
+}
+
+func (exec *LocalCacheExecutor) Commit(require bool) error {
+	exec.ClearLocalCache()
+	return exec.Executor.Commit(require)
+}
+
+func (exec *LocalCacheExecutor) Rollback(require bool) error {
+	exec.ClearLocalCache()
+	return exec.Executor.Rollback(require)
+}
+
+// ClearLocalCache 清空一级缓存
+func (exec *LocalCacheExecutor) ClearLocalCache() {
+	if len(exec.cache) > 0 {
+		exec.cache = map[cache.MetadataCacheKey]*util.CachedRows{}
+	}
+}
+
+// Unwrap 获得被包装的执行器
+func (exec *LocalCacheExecutor) Unwrap() Executor {
+	return exec.Executor
+}
@@ -19,24 +19,32 @@ package executor
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/acmestack/gobatis/common"
+	"github.com/acmestack/gobatis/connection"
 	"github.com/acmestack/gobatis/errors"
 	"github.com/acmestack/gobatis/reflection"
+	"github.com/acmestack/gobatis/statement"
 	"github.com/acmestack/gobatis/transaction"
 )
 
 type PrepareExecutor struct {
 	transaction transaction.Transaction
 	closed      bool
+	stmtCache   *StmtCache
+	txCache     *StmtCache
 }
 
-func NewPrepareExecutor(transaction transaction.Transaction) *PrepareExecutor {
-	return &PrepareExecutor{transaction: transaction}
+// NewPrepareExecutor 创建预编译语句执行器，stmtCache为数据库级别的语句缓存，可在多个执行器间共享；
+// stmtCache为nil时每次执行都会重新预编译语句
+func NewPrepareExecutor(transaction transaction.Transaction, stmtCache *StmtCache) *PrepareExecutor {
+	return &PrepareExecutor{transaction: transaction, stmtCache: stmtCache}
 }
 
 func (exec *PrepareExecutor) Close(rollback bool) {
 	defer func() {
+		exec.closeTxCache()
 		if exec.transaction != nil {
 			exec.transaction.Close()
 		}
@@ -59,12 +67,12 @@ func (exec *PrepareExecutor) Query(ctx context.Context, result reflection.Object
 		return errors.ExecutorGetConnectionError
 	}
 
-	//FIXME: stmt must be close, use stmtCache instead
-	stmt, err := conn.Prepare(sql)
-	defer stmt.Close()
+	stmt, release, err := exec.prepare(conn, sql)
 	if err != nil {
 		return err
 	}
+	defer release()
+
 	return stmt.Query(ctx, result, params...)
 }
 
@@ -78,22 +86,29 @@ func (exec *PrepareExecutor) Exec(ctx context.Context, sql string, params ...int
 		return nil, errors.ExecutorGetConnectionError
 	}
 
-	//FIXME: stmt must be close, use stmtCache instead
-	stmt, err := conn.Prepare(sql)
-	defer stmt.Close()
-
+	stmt, release, err := exec.prepare(conn, sql)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
+
 	return stmt.Exec(ctx, params...)
 }
 
-func (exec *PrepareExecutor) Begin() error {
+func (exec *PrepareExecutor) Begin(ctx context.Context, opts *sql.TxOptions) error {
 	if exec.closed {
 		return errors.ExecutorBeginError
 	}
 
-	return exec.transaction.Begin()
+	err := exec.transaction.Begin(ctx, opts)
+	if err != nil {
+		return err
+	}
+	//事务中的语句在事务结束时失效，使用独立的缓存
+	if exec.stmtCache != nil {
+		exec.txCache = exec.stmtCache.NewTxCache()
+	}
+	return nil
 }
 
 func (exec *PrepareExecutor) Commit(require bool) error {
@@ -102,6 +117,7 @@ func (exec *PrepareExecutor) Commit(require bool) error {
 	}
 
 	if require {
+		exec.closeTxCache()
 		return exec.transaction.Commit()
 	}
 
@@ -111,8 +127,34 @@ func (exec *PrepareExecutor) Commit(require bool) error {
 func (exec *PrepareExecutor) Rollback(require bool) error {
 	if !exec.closed {
 		if require {
+			exec.closeTxCache()
 			return exec.transaction.Rollback()
 		}
 	}
 	return nil
 }
+
+// prepare 从缓存中获得预编译语句，语句使用完毕后调用返回的release
+func (exec *PrepareExecutor) prepare(conn connection.Connection, sql string) (statement.Statement, func(), error) {
+	cache := exec.stmtCache
+	if exec.txCache != nil {
+		cache = exec.txCache
+	}
+
+	if cache != nil {
+		return cache.Get(sql, conn.Prepare)
+	}
+
+	stmt, err := conn.Prepare(sql)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stmt, stmt.Close, nil
+}
+
+func (exec *PrepareExecutor) closeTxCache() {
+	if exec.txCache != nil {
+		exec.txCache.Close()
+		exec.txCache = nil
+	}
+}
@@ -19,6 +19,7 @@ package executor
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/acmestack/gobatis/common"
 	"github.com/acmestack/gobatis/errors"
@@ -75,12 +76,12 @@ func (exec *SimpleExecutor) Exec(ctx context.Context, sql string, params ...inte
 	return conn.Exec(ctx, sql, params...)
 }
 
-func (exec *SimpleExecutor) Begin() error {
+func (exec *SimpleExecutor) Begin(ctx context.Context, opts *sql.TxOptions) error {
 	if exec.closed {
 		return errors.ExecutorBeginError
 	}
 
-	return exec.transaction.Begin()
+	return exec.transaction.Begin(ctx, opts)
 }
 
 func (exec *SimpleExecutor) Commit(require bool) error {
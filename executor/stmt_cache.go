@@ -0,0 +1,176 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package executor
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+
+	"github.com/acmestack/gobatis/statement"
+)
+
+const (
+	DefaultStmtCacheSize = 128
+)
+
+// StmtCacheStats 预编译语句缓存命中统计
+type StmtCacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type stmtCounter struct {
+	hits   uint64
+	misses uint64
+}
+
+type stmtEntry struct {
+	sql  string
+	stmt statement.Statement
+	//正在使用该语句的调用者数量
+	refs int
+	//已从缓存中淘汰，最后一个调用者释放时关闭
+	evicted bool
+}
+
+// StmtCache 预编译语句缓存，key为预编译的sql语句，超过容量时淘汰最近最少使用的语句
+type StmtCache struct {
+	capacity int
+	list     *list.List
+	items    map[string]*list.Element
+	counter  *stmtCounter
+	lock     sync.Mutex
+}
+
+// NewStmtCache 创建预编译语句缓存，capacity小于等于0时使用DefaultStmtCacheSize
+func NewStmtCache(capacity int) *StmtCache {
+	return newStmtCache(capacity, &stmtCounter{})
+}
+
+func newStmtCache(capacity int, counter *stmtCounter) *StmtCache {
+	if capacity <= 0 {
+		capacity = DefaultStmtCacheSize
+	}
+	return &StmtCache{
+		capacity: capacity,
+		list:     list.New(),
+		items:    map[string]*list.Element{},
+		counter:  counter,
+	}
+}
+
+// NewTxCache 创建事务内使用的缓存，与当前缓存共享命中统计
+func (cache *StmtCache) NewTxCache() *StmtCache {
+	return newStmtCache(cache.capacity, cache.counter)
+}
+
+// Get 获得sql对应的预编译语句，不存在时使用prepare创建并缓存
+// 语句使用完毕后必须调用返回的release，调用者不能关闭语句；
+// 语句被淘汰或缓存关闭时，在最后一个调用者release后才关闭
+func (cache *StmtCache) Get(sql string, prepare func(sql string) (statement.Statement, error)) (statement.Statement, func(), error) {
+	cache.lock.Lock()
+	if e, ok := cache.items[sql]; ok {
+		cache.list.MoveToFront(e)
+		entry := cache.acquire(e)
+		cache.lock.Unlock()
+		atomic.AddUint64(&cache.counter.hits, 1)
+		return entry.stmt, cache.releaseFunc(entry), nil
+	}
+	cache.lock.Unlock()
+	atomic.AddUint64(&cache.counter.misses, 1)
+
+	stmt, err := prepare(sql)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	//其他调用者已经缓存了相同的语句
+	if e, ok := cache.items[sql]; ok {
+		stmt.Close()
+		cache.list.MoveToFront(e)
+		entry := cache.acquire(e)
+		return entry.stmt, cache.releaseFunc(entry), nil
+	}
+	e := cache.list.PushFront(&stmtEntry{sql: sql, stmt: stmt})
+	cache.items[sql] = e
+	entry := cache.acquire(e)
+	for cache.list.Len() > cache.capacity {
+		cache.removeElement(cache.list.Back())
+	}
+	return stmt, cache.releaseFunc(entry), nil
+}
+
+func (cache *StmtCache) acquire(e *list.Element) *stmtEntry {
+	entry := e.Value.(*stmtEntry)
+	entry.refs++
+	return entry
+}
+
+// releaseFunc 释放语句的引用，多次调用只生效一次
+func (cache *StmtCache) releaseFunc(entry *stmtEntry) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cache.lock.Lock()
+			defer cache.lock.Unlock()
+
+			entry.refs--
+			if entry.evicted && entry.refs == 0 {
+				entry.stmt.Close()
+			}
+		})
+	}
+}
+
+// Len 缓存的语句数量
+func (cache *StmtCache) Len() int {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	return cache.list.Len()
+}
+
+// Stats 获得缓存命中统计，包含由该缓存创建的事务缓存
+func (cache *StmtCache) Stats() StmtCacheStats {
+	return StmtCacheStats{
+		Hits:   atomic.LoadUint64(&cache.counter.hits),
+		Misses: atomic.LoadUint64(&cache.counter.misses),
+	}
+}
+
+// Close 清除所有缓存的语句，未被使用的语句立即关闭，正在使用的语句在释放后关闭
+func (cache *StmtCache) Close() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	for cache.list.Len() > 0 {
+		cache.removeElement(cache.list.Back())
+	}
+}
+
+func (cache *StmtCache) removeElement(e *list.Element) {
+	entry := cache.list.Remove(e).(*stmtEntry)
+	delete(cache.items, entry.sql)
+	entry.evicted = true
+	if entry.refs == 0 {
+		entry.stmt.Close()
+	}
+}
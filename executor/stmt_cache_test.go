@@ -0,0 +1,107 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/acmestack/gobatis/common"
+	"github.com/acmestack/gobatis/reflection"
+	"github.com/acmestack/gobatis/statement"
+)
+
+type fakeStmt struct {
+	closed bool
+}
+
+func (s *fakeStmt) Query(ctx context.Context, result reflection.Object, params ...interface{}) error {
+	return nil
+}
+
+func (s *fakeStmt) Exec(ctx context.Context, params ...interface{}) (common.Result, error) {
+	return nil, nil
+}
+
+func (s *fakeStmt) Close() {
+	s.closed = true
+}
+
+func fakePrepare(stmts map[string]*fakeStmt) func(sql string) (statement.Statement, error) {
+	return func(sql string) (statement.Statement, error) {
+		s := &fakeStmt{}
+		stmts[sql] = s
+		return s, nil
+	}
+}
+
+func TestStmtCacheEvictInUse(t *testing.T) {
+	stmts := map[string]*fakeStmt{}
+	cache := NewStmtCache(1)
+
+	_, release1, err := cache.Get("sql1", fakePrepare(stmts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, release2, err := cache.Get("sql1", fakePrepare(stmts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	//sql1被淘汰时仍在使用
+	_, release3, err := cache.Get("sql2", fakePrepare(stmts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	release3()
+	if cache.Len() != 1 || stmts["sql1"].closed {
+		t.Fatal("expect evicted statement not closed while in use")
+	}
+	release1()
+	release1()
+	if stmts["sql1"].closed {
+		t.Fatal("expect statement closed after last release")
+	}
+	release2()
+	if !stmts["sql1"].closed {
+		t.Fatal("expect statement closed after last release")
+	}
+	if stmts["sql2"].closed {
+		t.Fatal("expect cached statement not closed")
+	}
+}
+
+func TestStmtCacheClose(t *testing.T) {
+	stmts := map[string]*fakeStmt{}
+	cache := NewStmtCache(0)
+
+	_, release1, _ := cache.Get("sql1", fakePrepare(stmts))
+	_, release2, _ := cache.Get("sql2", fakePrepare(stmts))
+	release2()
+	cache.Close()
+	if !stmts["sql2"].closed || stmts["sql1"].closed {
+		t.Fatal("expect only unused statement closed")
+	}
+	release1()
+	if !stmts["sql1"].closed {
+		t.Fatal("expect statement closed after release")
+	}
+	stats := cache.Stats()
+	if stats.Misses != 2 || stats.Hits != 0 {
+		t.Fatalf("unexpected stats %v", stats)
+	}
+}
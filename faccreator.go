@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/acmestack/gobatis/datasource"
+	"github.com/acmestack/gobatis/executor"
 	"github.com/acmestack/gobatis/factory"
 	"github.com/acmestack/gobatis/logging"
 )
@@ -75,6 +76,35 @@ func SetLog(logFunc logging.LogFunc) FacOpt {
 	}
 }
 
+// SetExecutorType 设置执行器类型
+func SetExecutorType(executorType executor.Type) FacOpt {
+	return func(f *factory.DefaultFactory) {
+		f.ExecutorType = executorType
+	}
+}
+
+// SetStmtCacheSize 设置预编译语句缓存的容量，仅在执行器类型为executor.TypePrepare时有效
+func SetStmtCacheSize(size int) FacOpt {
+	return func(f *factory.DefaultFactory) {
+		f.StmtCacheSize = size
+	}
+}
+
+// SetBatchSize 设置同一语句排队执行的最大数量，仅在执行器类型为executor.TypeBatch时有效
+func SetBatchSize(size int) FacOpt {
+	return func(f *factory.DefaultFactory) {
+		f.BatchSize = size
+	}
+}
+
+// SetLocalCache 设置是否开启一级缓存，开启后在session内缓存相同sql及参数的查询结果
+// 执行修改语句、提交或回滚事务时自动清空
+func SetLocalCache(enable bool) FacOpt {
+	return func(f *factory.DefaultFactory) {
+		f.LocalCache = enable
+	}
+}
+
 func SetDataSource(ds datasource.DataSource) FacOpt {
 	return func(f *factory.DefaultFactory) {
 		f.WithLock(func(fac *factory.DefaultFactory) {
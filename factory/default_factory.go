@@ -36,10 +36,20 @@ type DefaultFactory struct {
 	ConnMaxLifetime time.Duration
 	Log             logging.LogFunc
 
+	// ExecutorType 执行器类型，默认为executor.TypeSimple
+	ExecutorType executor.Type
+	// StmtCacheSize 使用executor.TypePrepare时预编译语句缓存的容量，小于等于0时使用executor.DefaultStmtCacheSize
+	StmtCacheSize int
+	// BatchSize 使用executor.TypeBatch时同一语句排队执行的最大数量，小于等于0时使用executor.DefaultBatchSize
+	BatchSize int
+	// LocalCache 是否开启一级缓存，开启后在session内缓存相同sql及参数的查询结果
+	LocalCache bool
+
 	DataSource datasource.DataSource
 
-	db    *sql.DB
-	mutex sync.Mutex
+	db        *sql.DB
+	stmtCache *executor.StmtCache
+	mutex     sync.Mutex
 }
 
 func (factory *DefaultFactory) Open(ds datasource.DataSource) error {
@@ -64,10 +74,16 @@ func (factory *DefaultFactory) Open(ds datasource.DataSource) error {
 	db.SetConnMaxLifetime(factory.ConnMaxLifetime)
 
 	factory.db = db
+	if factory.ExecutorType == executor.TypePrepare {
+		factory.stmtCache = executor.NewStmtCache(factory.StmtCacheSize)
+	}
 	return nil
 }
 
 func (factory *DefaultFactory) Close() error {
+	if factory.stmtCache != nil {
+		factory.stmtCache.Close()
+	}
 	if factory.db != nil {
 		return factory.db.Close()
 	}
@@ -83,7 +99,27 @@ func (factory *DefaultFactory) CreateTransaction() transaction.Transaction {
 }
 
 func (factory *DefaultFactory) CreateExecutor(transaction transaction.Transaction) executor.Executor {
-	return executor.NewSimpleExecutor(transaction)
+	var e executor.Executor
+	switch factory.ExecutorType {
+	case executor.TypePrepare:
+		e = executor.NewPrepareExecutor(transaction, factory.stmtCache)
+	case executor.TypeBatch:
+		e = executor.NewBatchExecutor(transaction, factory.BatchSize)
+	default:
+		e = executor.NewSimpleExecutor(transaction)
+	}
+	if factory.LocalCache {
+		e = executor.NewLocalCacheExecutor(e)
+	}
+	return e
+}
+
+// StmtCacheStats 获得预编译语句缓存的命中统计，未使用executor.TypePrepare时返回空统计
+func (factory *DefaultFactory) StmtCacheStats() executor.StmtCacheStats {
+	if factory.stmtCache == nil {
+		return executor.StmtCacheStats{}
+	}
+	return factory.stmtCache.Stats()
 }
 
 func (factory *DefaultFactory) CreateSession() session.SqlSession {
@@ -0,0 +1,338 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gobatis
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/logging"
+	"github.com/acmestack/gobatis/parsing"
+	"github.com/acmestack/gobatis/parsing/sqlparser"
+	"github.com/acmestack/gobatis/reflection"
+)
+
+// GeneratedKeysMode 数据库获得自动生成主键的方式
+type GeneratedKeysMode int
+
+const (
+	// GeneratedKeysFirstId LastInsertId返回多行插入的第一个id，如mysql
+	GeneratedKeysFirstId GeneratedKeysMode = iota
+	// GeneratedKeysLastId LastInsertId返回多行插入的最后一个id，如sqlite
+	GeneratedKeysLastId
+	// GeneratedKeysReturning 不支持LastInsertId，在语句后添加RETURNING子句获得主键，如postgresql
+	GeneratedKeysReturning
+)
+
+var gGeneratedKeysMap = map[string]GeneratedKeysMode{
+	"mysql":    GeneratedKeysFirstId,   //mysql
+	"sqlite3":  GeneratedKeysLastId,    //sqlite
+	"postgres": GeneratedKeysReturning, //postgresql
+}
+
+// RegisterGeneratedKeysMode 注册数据库驱动获得自动生成主键的方式，返回是否覆盖了已有的注册
+func RegisterGeneratedKeysMode(driverName string, mode GeneratedKeysMode) bool {
+	_, ok := gGeneratedKeysMap[driverName]
+	gGeneratedKeysMap[driverName] = mode
+	return ok
+}
+
+// SelectGeneratedKeysMode 获得数据库驱动获得自动生成主键的方式，未注册的驱动使用LastInsertId的第一个id
+func SelectGeneratedKeysMode(driverName string) GeneratedKeysMode {
+	if v, ok := gGeneratedKeysMap[driverName]; ok {
+		return v
+	}
+	return GeneratedKeysFirstId
+}
+
+// useReturning 语句是否需要使用RETURNING子句获得自动生成的主键
+func (baseRunner *BaseRunner) useReturning(stmt *parsing.Statement) bool {
+	return stmt != nil && stmt.UseGeneratedKeys && len(stmt.KeyProperty) > 0 &&
+		SelectGeneratedKeysMode(baseRunner.driver) == GeneratedKeysReturning
+}
+
+// insertReturning 在insert语句后添加RETURNING子句，将返回的主键依次写回参数，返回插入的行数
+// 语句中已经包含RETURNING子句时直接执行，返回的列需要包含keyColumn
+func (baseRunner *BaseRunner) insertReturning(ctx context.Context, md *sqlparser.Metadata, stmt *parsing.Statement) (int64, error) {
+	columns := stmt.KeyColumn
+	if len(columns) == 0 {
+		columns = make([]string, len(stmt.KeyProperty))
+		for i, p := range stmt.KeyProperty {
+			columns[i] = propertyName(p)
+		}
+	}
+	var rows []map[string]interface{}
+	obj, err := baseRunner.config.ParseObject(&rows)
+	if err != nil {
+		return 0, err
+	}
+	//insert通过查询执行，避免一级缓存返回结果或缓存旧数据
+	baseRunner.session.ClearLocalCache()
+	defer baseRunner.session.ClearLocalCache()
+	err = baseRunner.session.Query(ctx, obj, appendReturning(md.PrepareSql, columns), md.Params...)
+	if err != nil {
+		return 0, err
+	}
+	writeKeys(baseRunner.params, stmt.KeyProperty, columns, rows)
+	return int64(len(rows)), nil
+}
+
+// appendReturning 去除语句末尾的分号、空白及注释后添加RETURNING子句，语句中已经包含RETURNING时不添加
+func appendReturning(sql string, columns []string) string {
+	end := 0
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			j := strings.IndexByte(sql[i+1:], c)
+			if j < 0 {
+				return sql + " RETURNING " + strings.Join(columns, ", ")
+			}
+			i += j + 1
+			end = i + 1
+		case strings.HasPrefix(sql[i:], "--"):
+			j := strings.IndexByte(sql[i:], '\n')
+			if j < 0 {
+				j = len(sql) - i
+			}
+			i += j
+		case strings.HasPrefix(sql[i:], "/*"):
+			j := strings.Index(sql[i+2:], "*/")
+			if j < 0 {
+				j = len(sql) - i
+			}
+			i += j + 3
+		case c == ';' || unicode.IsSpace(rune(c)):
+		case isWordChar(c):
+			j := i + 1
+			for j < len(sql) && isWordChar(sql[j]) {
+				j++
+			}
+			if strings.EqualFold(sql[i:j], "RETURNING") {
+				return sql
+			}
+			i = j - 1
+			end = j
+		default:
+			end = i + 1
+		}
+	}
+	return sql[:end] + " RETURNING " + strings.Join(columns, ", ")
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// writeGeneratedIds 使用LastInsertId计算每一行的主键并写回参数，无法确定每一行的主键时不写回
+// LastInsertId只能获得一个自增列，配置多个keyProperty时只写回第一个；
+// 批量执行器排队中的语句影响行数及id均为0，此时不写回
+func (baseRunner *BaseRunner) writeGeneratedIds(stmt *parsing.Statement, count, id int64) {
+	if stmt == nil || !stmt.UseGeneratedKeys || len(stmt.KeyProperty) == 0 {
+		return
+	}
+	if id == 0 {
+		if count == 0 {
+			baseRunner.log(logging.WARN, "statement %s may be queued by batch executor, skip generated keys", stmt.Id)
+		}
+		return
+	}
+	if len(stmt.KeyProperty) > 1 {
+		baseRunner.log(logging.WARN, "statement %s LastInsertId only supports one key, skip key properties %v",
+			stmt.Id, stmt.KeyProperty[1:])
+	}
+	targets := keyTargets(baseRunner.params)
+	n := int64(len(targets))
+	if n == 0 {
+		return
+	}
+	if n > 1 && count != n {
+		baseRunner.log(logging.WARN, "insert count %d not match param count %d, skip generated keys", count, n)
+		return
+	}
+	first := id
+	if SelectGeneratedKeysMode(baseRunner.driver) == GeneratedKeysLastId {
+		first = id - n + 1
+	}
+	for i, t := range targets {
+		setKey(t, stmt.KeyProperty[0], first+int64(i))
+	}
+}
+
+// selectKey 执行语句配置的指定order的selectKey，并将结果写回参数
+func (baseRunner *BaseRunner) selectKey(ctx context.Context, order string) error {
+	stmt := baseRunner.statement()
+	if stmt == nil || stmt.SelectKey == nil || stmt.SelectKey.Order != order || stmt.SelectKey.Sql == nil {
+		return nil
+	}
+	sk := stmt.SelectKey
+	md, err := sk.Sql.ParseMetadata(baseRunner.driver, baseRunner.params...)
+	if err != nil {
+		return err
+	}
+	var rows []map[string]interface{}
+	obj, err := baseRunner.config.ParseObject(&rows)
+	if err != nil {
+		return err
+	}
+	//避免一级缓存返回之前的结果
+	baseRunner.session.ClearLocalCache()
+	err = baseRunner.session.Query(ctx, obj, md.PrepareSql, md.Params...)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return errors.SelectKeyEmptyResult
+	}
+	writeKeys(baseRunner.params, sk.KeyProperty, sk.KeyColumn, rows[:1])
+	return nil
+}
+
+// selectKeyBefore 执行order为BEFORE的selectKey，结果写回参数后重新生成sql
+func (baseRunner *BaseRunner) selectKeyBefore() error {
+	stmt := baseRunner.statement()
+	if stmt == nil || stmt.SelectKey == nil || stmt.SelectKey.Order != parsing.SelectKeyBefore || baseRunner.metadata == nil {
+		return nil
+	}
+	ctx, cancel := baseRunner.statementContext(baseRunner.ctx)
+	defer cancel()
+	if err := baseRunner.selectKey(ctx, parsing.SelectKeyBefore); err != nil {
+		return err
+	}
+	baseRunner.Param(baseRunner.params...)
+	return nil
+}
+
+// writeKeys 将查询返回的每一行按顺序写回参数，keyColumn为空时使用keyProperty作为列名
+func writeKeys(params []interface{}, properties, columns []string, rows []map[string]interface{}) {
+	targets := keyTargets(params)
+	for i := 0; i < len(rows) && i < len(targets); i++ {
+		for j, p := range properties {
+			column := propertyName(p)
+			if j < len(columns) {
+				column = columns[j]
+			}
+			v, ok := rowValue(rows[i], column)
+			if !ok && len(properties) == 1 && len(rows[i]) == 1 {
+				//只有一列时直接使用该列的值
+				for _, only := range rows[i] {
+					v, ok = only, true
+				}
+			}
+			if ok {
+				setKey(targets[i], p, v)
+			}
+		}
+	}
+}
+
+// keyTargets 获得写回主键的对象，参数为struct指针或map时返回该对象，参数为slice时返回每一个元素
+func keyTargets(params []interface{}) []reflect.Value {
+	if len(params) == 0 || params[0] == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(params[0])
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		if rv.CanSet() {
+			return []reflect.Value{rv}
+		}
+	case reflect.Map:
+		return []reflect.Value{rv}
+	case reflect.Slice, reflect.Array:
+		var ret []reflect.Value
+		for i := 0; i < rv.Len(); i++ {
+			elem := rv.Index(i)
+			for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
+				elem = elem.Elem()
+			}
+			if (elem.Kind() == reflect.Struct && elem.CanSet()) || elem.Kind() == reflect.Map {
+				ret = append(ret, elem)
+			}
+		}
+		return ret
+	}
+	logging.Warn("generated keys need pointer of struct, slice or map, get %v\n", rv.Type())
+	return nil
+}
+
+// setKey 设置对象的主键，struct按字段名、column tag或忽略大小写的字段名匹配
+func setKey(target reflect.Value, property string, v interface{}) {
+	name := propertyName(property)
+	if target.Kind() == reflect.Map {
+		if target.IsNil() || target.Type().Key().Kind() != reflect.String {
+			return
+		}
+		value := reflect.ValueOf(v)
+		if !value.IsValid() || !value.Type().AssignableTo(target.Type().Elem()) {
+			return
+		}
+		target.SetMapIndex(reflect.ValueOf(name).Convert(target.Type().Key()), value)
+		return
+	}
+	info, err := reflection.GetReflectStructInfo(target.Type(), target)
+	if err != nil {
+		return
+	}
+	fieldName := info.FieldNameMap[name]
+	if fieldName == "" {
+		if _, ok := target.Type().FieldByName(name); ok {
+			fieldName = name
+		}
+	}
+	if fieldName == "" {
+		for column, field := range info.FieldNameMap {
+			if strings.EqualFold(column, name) || strings.EqualFold(field, name) {
+				fieldName = field
+				break
+			}
+		}
+	}
+	if fieldName == "" {
+		logging.Warn("key property %s not found in %v\n", property, target.Type())
+		return
+	}
+	reflection.SetValue(target.FieldByName(fieldName), v)
+}
+
+// rowValue 获得列的值，列名不区分大小写
+func rowValue(row map[string]interface{}, column string) (interface{}, bool) {
+	if v, ok := row[column]; ok {
+		return v, true
+	}
+	for k, v := range row {
+		if strings.EqualFold(k, column) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// propertyName 获得keyProperty的字段名，如TestTable.id中的id
+func propertyName(property string) string {
+	if i := strings.LastIndex(property, "."); i != -1 {
+		return property[i+1:]
+	}
+	return property
+}
@@ -0,0 +1,255 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gobatis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/parsing/sqlparser"
+)
+
+// MapperStatementTag mapper struct中函数字段指定语句id的tag
+const MapperStatementTag = "statement"
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// mapperMethod mapper struct中绑定语句的函数
+type mapperMethod struct {
+	sqlId string
+	//语句类型，语句不是xml mapper中的语句时为空，调用时根据生成的sql判断
+	action string
+	fnType reflect.Type
+	hasCtx bool
+}
+
+// BindMapper 使用字段statement tag指定的语句实现mapper struct中的函数字段
+// 函数的第一个参数可以是context.Context，其余参数作为语句的参数，最后一个返回值必须为error：
+// select返回一个结果，类型与Result的参数类型一致；insert可以返回影响的行数及最后插入的id；update、delete可以返回影响的行数
+// 调用时使用context中的Session（如Tx中的context），context中没有Session时创建新的Session
+func (sessionManager *SessionManager) BindMapper(mapper interface{}) error {
+	return bindMapper(sessionManager.config, mapper, sessionManager.contextSession)
+}
+
+// contextSession 获得context中的Session，不存在时创建新的Session
+func (sessionManager *SessionManager) contextSession(ctx context.Context) *Session {
+	if sess, ok := ctx.Value(ContextSessionKey).(*Session); ok && sess != nil {
+		return sess
+	}
+	return sessionManager.createSession(ctx)
+}
+
+// BindMapper 实现mapper struct中的函数字段，所有调用都使用当前Session，规则与SessionManager.BindMapper相同
+func (session *Session) BindMapper(mapper interface{}) error {
+	return bindMapper(session.config, mapper, func(ctx context.Context) *Session {
+		return session
+	})
+}
+
+func bindMapper(config *Configuration, mapper interface{}, session func(ctx context.Context) *Session) error {
+	rv := reflect.ValueOf(mapper)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.MapperNotStructPointer
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		sqlId, ok := field.Tag.Lookup(MapperStatementTag)
+		if !ok {
+			continue
+		}
+		if field.Type.Kind() != reflect.Func || !rv.Field(i).CanSet() {
+			return fmt.Errorf("%w: field %s must be an exported function", errors.MapperFuncNotSupport, field.Name)
+		}
+		m, err := newMapperMethod(config, sqlId, field.Type)
+		if err != nil {
+			return fmt.Errorf("%w: field %s statement %s", err, field.Name, sqlId)
+		}
+		rv.Field(i).Set(reflect.MakeFunc(field.Type, m.caller(session)))
+	}
+	return nil
+}
+
+func newMapperMethod(config *Configuration, sqlId string, fnType reflect.Type) (*mapperMethod, error) {
+	if _, ok := config.FindSqlParser(sqlId); !ok {
+		return nil, errors.StatementNotFound
+	}
+	ret := &mapperMethod{
+		sqlId:  sqlId,
+		action: statementAction(config, sqlId),
+		fnType: fnType,
+		hasCtx: fnType.NumIn() > 0 && fnType.In(0) == contextType,
+	}
+	if err := ret.check(ret.action); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// check 检查函数的返回值是否支持语句类型
+func (m *mapperMethod) check(action string) error {
+	n := m.fnType.NumOut()
+	if n == 0 || m.fnType.Out(n-1) != errorType {
+		return errors.MapperFuncNotSupport
+	}
+	switch action {
+	case sqlparser.SELECT:
+		if n != 2 {
+			return errors.MapperFuncNotSupport
+		}
+	case sqlparser.INSERT:
+		if n > 3 || !isIntOuts(m.fnType, n-1) {
+			return errors.MapperFuncNotSupport
+		}
+	case sqlparser.UPDATE, sqlparser.DELETE:
+		if n > 2 || !isIntOuts(m.fnType, n-1) {
+			return errors.MapperFuncNotSupport
+		}
+	}
+	return nil
+}
+
+func isIntOuts(fnType reflect.Type, n int) bool {
+	for i := 0; i < n; i++ {
+		switch fnType.Out(i).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (m *mapperMethod) caller(session func(ctx context.Context) *Session) func(args []reflect.Value) []reflect.Value {
+	return func(args []reflect.Value) []reflect.Value {
+		var ctx context.Context
+		if m.hasCtx {
+			ctx, _ = args[0].Interface().(context.Context)
+			args = args[1:]
+		}
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		sess := session(ctx)
+		if !m.hasCtx {
+			ctx = sess.ctx
+		}
+		return m.call(sess, ctx, m.params(args))
+	}
+}
+
+// params 获得语句参数，可变参数展开后传入
+func (m *mapperMethod) params(args []reflect.Value) []interface{} {
+	var ret []interface{}
+	for i, arg := range args {
+		if m.fnType.IsVariadic() && i == len(args)-1 {
+			for j := 0; j < arg.Len(); j++ {
+				ret = append(ret, arg.Index(j).Interface())
+			}
+			continue
+		}
+		ret = append(ret, arg.Interface())
+	}
+	return ret
+}
+
+func (m *mapperMethod) call(sess *Session, ctx context.Context, params []interface{}) []reflect.Value {
+	n := m.fnType.NumOut()
+	outs := make([]reflect.Value, n)
+	for i := 0; i < n; i++ {
+		outs[i] = reflect.Zero(m.fnType.Out(i))
+	}
+
+	action := m.action
+	if action == "" {
+		//template等语句在生成sql后才能确定类型
+		parser, ok := sess.config.FindSqlParser(m.sqlId)
+		if !ok {
+			return setError(outs, errors.StatementNotFound)
+		}
+		md, err := parser.ParseMetadata(sess.driver, params...)
+		if err == nil {
+			action = md.Action
+			err = m.check(action)
+		}
+		if err != nil {
+			return setError(outs, err)
+		}
+	}
+
+	var err error
+	switch action {
+	case sqlparser.SELECT:
+		t := m.fnType.Out(0)
+		runner := sess.Select(m.sqlId).Context(ctx).Param(params...)
+		if t.Kind() == reflect.Ptr {
+			//指针类型的结果创建新的对象
+			v := reflect.New(t.Elem())
+			err = runner.Result(v.Interface())
+			outs[0] = v
+		} else {
+			v := reflect.New(t)
+			err = runner.Result(v.Interface())
+			outs[0] = v.Elem()
+		}
+	case sqlparser.INSERT:
+		var count int64
+		runner := sess.Insert(m.sqlId).Context(ctx).Param(params...)
+		err = runner.Result(&count)
+		setInt(outs, 0, n-1, count)
+		setInt(outs, 1, n-1, runner.LastInsertId())
+	default:
+		var count int64
+		if action == sqlparser.UPDATE {
+			err = sess.Update(m.sqlId).Context(ctx).Param(params...).Result(&count)
+		} else {
+			err = sess.Delete(m.sqlId).Context(ctx).Param(params...).Result(&count)
+		}
+		setInt(outs, 0, n-1, count)
+	}
+	return setError(outs, err)
+}
+
+// setInt 设置第i个返回值，i超出返回值个数时忽略
+func setInt(outs []reflect.Value, i, n int, v int64) {
+	if i >= n {
+		return
+	}
+	out := reflect.New(outs[i].Type()).Elem()
+	switch out.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		out.SetUint(uint64(v))
+	default:
+		out.SetInt(v)
+	}
+	outs[i] = out
+}
+
+func setError(outs []reflect.Value, err error) []reflect.Value {
+	if err != nil {
+		outs[len(outs)-1] = reflect.ValueOf(&err).Elem()
+	}
+	return outs
+}
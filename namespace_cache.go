@@ -0,0 +1,103 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gobatis
+
+import (
+	"context"
+
+	"github.com/acmestack/gobatis/cache"
+	"github.com/acmestack/gobatis/parsing/sqlparser"
+	"github.com/acmestack/gobatis/reflection"
+	"github.com/acmestack/gobatis/util"
+)
+
+// query 执行查询，语句所在namespace配置了二级缓存时优先使用缓存的结果
+// 事务中的查询结果不写入缓存，避免缓存未提交的数据；事务中修改过的namespace不使用缓存
+func (baseRunner *BaseRunner) query(ctx context.Context, obj reflection.Object, md *sqlparser.Metadata) error {
+	stmt := baseRunner.statement()
+	if stmt == nil || stmt.Cache == nil {
+		return baseRunner.session.Query(ctx, obj, md.PrepareSql, md.Params...)
+	}
+	if stmt.FlushCache {
+		stmt.Cache.Clear()
+	}
+	if !stmt.UseCache || baseRunner.tx.flushed(stmt.Cache) {
+		return baseRunner.session.Query(ctx, obj, md.PrepareSql, md.Params...)
+	}
+
+	key := baseRunner.cacheKey(md)
+	if v, ok := stmt.Cache.Get(key); ok {
+		if rows, ok := v.(*util.CachedRows); ok {
+			rows.Scan(obj)
+			return nil
+		}
+	}
+
+	recorder := util.NewRowsRecorder()
+	err := baseRunner.session.Query(ctx, recorder, md.PrepareSql, md.Params...)
+	if err != nil {
+		return err
+	}
+	if baseRunner.tx == nil {
+		stmt.Cache.Put(key, recorder.Rows())
+	}
+	recorder.Rows().Scan(obj)
+	return nil
+}
+
+// cacheKey 计算查询结果的缓存key，多个SessionManager可能共享同一个Configuration，key中包含数据源信息
+func (baseRunner *BaseRunner) cacheKey(md *sqlparser.Metadata) string {
+	key := string(cache.CalcParamsKey(md.PrepareSql, md.Params...))
+	if baseRunner.dataSource == nil {
+		return key
+	}
+	return baseRunner.dataSource.DriverName() + "\x00" + baseRunner.dataSource.DriverInfo() + "\x00" + key
+}
+
+// flushCache 修改语句执行后清空namespace的二级缓存，事务中的修改在事务结束时再次清空
+func (baseRunner *BaseRunner) flushCache() {
+	stmt := baseRunner.statement()
+	if stmt == nil || stmt.Cache == nil || !stmt.FlushCache {
+		return
+	}
+	stmt.Cache.Clear()
+	if baseRunner.tx != nil {
+		baseRunner.tx.flushCaches = append(baseRunner.tx.flushCaches, stmt.Cache)
+	}
+}
+
+// flushed 事务中是否执行过修改该缓存所在namespace的语句，不在事务中时返回false
+func (status *txStatus) flushed(c cache.Cache) bool {
+	if status == nil {
+		return false
+	}
+	for _, v := range status.flushCaches {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+// clearCaches 事务结束时清空事务中修改过的namespace缓存
+func (status *txStatus) clearCaches() {
+	for _, c := range status.flushCaches {
+		c.Clear()
+	}
+	status.flushCaches = nil
+}
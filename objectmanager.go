@@ -26,32 +26,35 @@ import (
 
 type TableName string
 
+// ObjectCache 模型解析结果的缓存
 type ObjectCache struct {
 	objCache map[string]reflection.Object
 	lock     sync.Mutex
 }
 
-var globalObjectCache = ObjectCache{
-	objCache: map[string]reflection.Object{},
+func NewObjectCache() *ObjectCache {
+	return &ObjectCache{
+		objCache: map[string]reflection.Object{},
+	}
 }
 
-func findObject(bean interface{}) reflection.Object {
+func (cache *ObjectCache) findObject(bean interface{}) reflection.Object {
 	classname := reflection.GetBeanClassName(bean)
-	globalObjectCache.lock.Lock()
-	defer globalObjectCache.lock.Unlock()
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
 
-	return globalObjectCache.objCache[classname]
+	return cache.objCache[classname]
 }
 
-func cacheObject(obj reflection.Object) {
-	globalObjectCache.lock.Lock()
-	defer globalObjectCache.lock.Unlock()
+func (cache *ObjectCache) cacheObject(obj reflection.Object) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
 
-	globalObjectCache.objCache[obj.GetClassName()] = obj
+	cache.objCache[obj.GetClassName()] = obj
 }
 
-func ParseObject(bean interface{}) (reflection.Object, error) {
-	obj := findObject(bean)
+func (cache *ObjectCache) ParseObject(bean interface{}) (reflection.Object, error) {
+	obj := cache.findObject(bean)
 	var err error
 	if obj == nil {
 		obj, err = reflection.GetObjectInfo(bean)
@@ -59,31 +62,40 @@ func ParseObject(bean interface{}) (reflection.Object, error) {
 			return nil, err
 		}
 
-		cacheObject(obj)
+		cache.cacheObject(obj)
 	}
 	obj = obj.New()
 	obj.ResetValue(reflection.ReflectValue(bean))
 	return obj, nil
 }
 
-// RegisterModel 注册struct模型，模型描述了column和field之间的关联关系；
-// 目前已非必要条件
-func RegisterModel(model interface{}) error {
-	return RegisterModelWithName("", model)
-}
-
-func RegisterModelWithName(name string, model interface{}) error {
+func (cache *ObjectCache) RegisterModelWithName(name string, model interface{}) error {
 	tableInfo, err := reflection.GetObjectInfo(model)
 	if err != nil {
 		return errors.ParseModelTableInfoFailed
 	}
 
-	globalObjectCache.lock.Lock()
-	defer globalObjectCache.lock.Unlock()
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
 
 	if name == "" {
 		name = tableInfo.GetClassName()
 	}
-	globalObjectCache.objCache[name] = tableInfo
+	cache.objCache[name] = tableInfo
 	return nil
 }
+
+// ParseObject 使用默认Configuration的模型缓存解析对象
+func ParseObject(bean interface{}) (reflection.Object, error) {
+	return defaultConfiguration.ParseObject(bean)
+}
+
+// RegisterModel 注册struct模型，模型描述了column和field之间的关联关系；
+// 目前已非必要条件
+func RegisterModel(model interface{}) error {
+	return RegisterModelWithName("", model)
+}
+
+func RegisterModelWithName(name string, model interface{}) error {
+	return defaultConfiguration.RegisterModelWithName(name, model)
+}
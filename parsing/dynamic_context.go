@@ -0,0 +1,118 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsing
+
+import "reflect"
+
+// DynamicContext 格式化动态元素时使用的参数，元素可以在格式化时绑定新的参数，如foreach的集合元素
+type DynamicContext struct {
+	getValue ValueFunc
+	// 绑定参数保存的参数map，与生成sql后解析参数使用的map相同
+	params map[string]interface{}
+	// 生成唯一参数名的序号，子作用域共享
+	seq *int
+	// 当前作用域中绑定的变量，如<bind>的结果，之后的元素及子作用域中可见
+	vars map[string]interface{}
+	// 是否为子作用域，子作用域中绑定的变量不保存到params
+	scoped bool
+}
+
+// NewDynamicContext 创建格式化上下文，params为参数名与参数值的map，绑定的参数也保存到params中
+func NewDynamicContext(params map[string]interface{}) *DynamicContext {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return &DynamicContext{
+		getValue: func(key string) (interface{}, bool) {
+			o, ok := params[key]
+			//参数解析时map的值保存为reflect.Value
+			if rv, isValue := o.(reflect.Value); isValue {
+				if !rv.IsValid() || !rv.CanInterface() {
+					return nil, ok
+				}
+				o = rv.Interface()
+			}
+			return o, ok
+		},
+		params: params,
+		seq:    new(int),
+	}
+}
+
+// NewValueContext 使用ValueFunc创建格式化上下文，绑定的参数不会被使用
+func NewValueContext(getValue ValueFunc) *DynamicContext {
+	return &DynamicContext{
+		getValue: getValue,
+		params:   map[string]interface{}{},
+		seq:      new(int),
+	}
+}
+
+// Value 通过参数名获得参数值，参数不存在时返回false
+func (ctx *DynamicContext) Value(key string) (interface{}, bool) {
+	if v, ok := ctx.vars[key]; ok {
+		return v, true
+	}
+	return ctx.getValue(key)
+}
+
+// Bind 绑定新的参数，之后可以在#{key}中使用
+func (ctx *DynamicContext) Bind(key string, value interface{}) {
+	ctx.params[key] = value
+}
+
+// BindVar 在当前作用域中绑定变量，之后的元素及子作用域中可以使用
+// 不在子作用域中时同时绑定为参数，可以在#{key}中使用；子作用域中的变量由创建作用域的元素绑定为唯一的参数名，如foreach
+func (ctx *DynamicContext) BindVar(key string, value interface{}) {
+	if ctx.vars == nil {
+		ctx.vars = map[string]interface{}{}
+	}
+	ctx.vars[key] = value
+	if !ctx.scoped {
+		ctx.Bind(key, value)
+	}
+}
+
+// HasVar 当前作用域中是否绑定了变量，不包含父作用域中的变量
+func (ctx *DynamicContext) HasVar(key string) bool {
+	_, ok := ctx.vars[key]
+	return ok
+}
+
+// NextSeq 获得递增的序号，用于生成唯一的参数名
+func (ctx *DynamicContext) NextSeq() int {
+	n := *ctx.seq
+	*ctx.seq++
+	return n
+}
+
+// Scope 创建子作用域，优先使用getValue获得参数，getValue返回false时使用当前作用域的参数及变量
+func (ctx *DynamicContext) Scope(getValue ValueFunc) *DynamicContext {
+	parent := ctx.Value
+	return &DynamicContext{
+		getValue: func(key string) (interface{}, bool) {
+			if v, ok := getValue(key); ok {
+				return v, true
+			}
+			return parent(key)
+		},
+		params: ctx.params,
+		seq:    ctx.seq,
+		scoped: true,
+	}
+}
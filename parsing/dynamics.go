@@ -33,9 +33,63 @@ type DynamicElement interface {
 	Format(func(key string) string) string
 }
 
+// ValueFunc 通过参数名获得参数的原始值，参数不存在时返回false
+type ValueFunc func(key string) (interface{}, bool)
+
+// ValueElement 使用参数原始值格式化的动态元素，如需要按类型计算test表达式的<if>元素
+type ValueElement interface {
+	FormatValue(ctx *DynamicContext) string
+}
+
+// FormatElement 格式化动态元素，元素实现ValueElement时使用参数原始值
+func FormatElement(de DynamicElement, ctx *DynamicContext) string {
+	if ve, ok := de.(ValueElement); ok {
+		return ve.FormatValue(ctx)
+	}
+	return de.Format(StringGetter(ctx.Value))
+}
+
+// StringGetter 将ValueFunc转换为获得字符串参数的方法，参数不存在或为零值时间时返回空字符串
+func StringGetter(getValue ValueFunc) GetFunc {
+	return func(key string) string {
+		o, ok := getValue(key)
+		if !ok {
+			return ""
+		}
+		if str, ok := o.(string); ok {
+			return str
+		}
+
+		//zero time convert to empty string (for <if> </if> element)
+		if ti, ok := o.(time.Time); ok {
+			if ti.IsZero() {
+				return ""
+			} else {
+				return ti.String()
+			}
+		}
+
+		var str string
+		reflection.SafeSetValue(reflect.ValueOf(&str), o)
+		return str
+	}
+}
+
+// ValueGetter 将获得字符串参数的方法转换为ValueFunc，空字符串作为参数不存在
+func ValueGetter(getFunc func(key string) string) ValueFunc {
+	return func(key string) (interface{}, bool) {
+		s := getFunc(key)
+		return s, s != ""
+	}
+}
+
 type DynamicData struct {
 	OriginData     string
 	DynamicElemMap map[string]DynamicElement
+	// Elements 按顺序保存的文本及动态元素节点树，不为nil时使用Elements代替OriginData生成sql
+	Elements []DynamicElement
+	// Statement mapper文件中语句的配置，直接使用sql语句时为nil
+	Statement *Statement
 }
 
 func (dynamicData *DynamicData) Replace(params ...interface{}) string {
@@ -45,42 +99,39 @@ func (dynamicData *DynamicData) Replace(params ...interface{}) string {
 
 // ReplaceWithMap 需要外部确保param是一个struct
 func (dynamicData *DynamicData) ReplaceWithMap(objParams map[string]interface{}) string {
-	if len(dynamicData.DynamicElemMap) == 0 || len(objParams) == 0 {
+	params := make(map[string]interface{}, len(objParams))
+	for k, v := range objParams {
+		params[k] = v
+	}
+	return dynamicData.replaceWithMap(params)
+}
+
+func (dynamicData *DynamicData) replaceWithMap(objParams map[string]interface{}) string {
+	if (len(dynamicData.DynamicElemMap) == 0 && len(dynamicData.Elements) == 0) || len(objParams) == 0 {
 		logging.Info("map is empty")
 		//return dynamicData.OriginData
 	}
 
-	getFunc := func(s string) string {
-		if o, ok := objParams[s]; ok {
-			if str, ok := o.(string); ok {
-				return str
-			}
-
-			//zero time convert to empty string (for <if> </if> element)
-			if ti, ok := o.(time.Time); ok {
-				if ti.IsZero() {
-					return ""
-				} else {
-					return ti.String()
-				}
+	ctx := NewDynamicContext(objParams)
+	if dynamicData.Elements != nil {
+		ret := make([]string, 0, len(dynamicData.Elements))
+		for _, v := range dynamicData.Elements {
+			if s := strings.TrimSpace(FormatElement(v, ctx)); s != "" {
+				ret = append(ret, s)
 			}
-
-			var str string
-			reflection.SafeSetValue(reflect.ValueOf(&str), o)
-			return str
 		}
-		return ""
+		return strings.Join(ret, " ")
 	}
 
 	ret := dynamicData.OriginData
 	for k, v := range dynamicData.DynamicElemMap {
-		ret = strings.Replace(ret, k, v.Format(getFunc), -1)
+		ret = strings.Replace(ret, k, FormatElement(v, ctx), -1)
 	}
 	return ret
 }
 
 func (dynamicData *DynamicData) ParseMetadata(driverName string, params ...interface{}) (*sqlparser.Metadata, error) {
 	paramMap := reflection.ParseParams(params...)
-	sqlStr := dynamicData.ReplaceWithMap(paramMap)
+	sqlStr := dynamicData.replaceWithMap(paramMap)
 	return sqlparser.ParseWithParamMap(driverName, sqlStr, paramMap)
 }
@@ -0,0 +1,579 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Resolver 通过参数名获得参数值
+type Resolver func(name string) (interface{}, bool)
+
+// Expr 编译后的表达式，可以并发执行
+// 支持：
+//
+//	逻辑运算：&& || !（也可以使用and or not），使用()分组
+//	比较运算：== != < > <= >=（也可以使用eq neq lt gt lte gte），数字按数值比较，时间按时间先后比较
+//	算术运算：+ - * / %，+的操作数存在字符串时连接字符串
+//	常量：数字、字符串（单引号或双引号）、true、false、nil（null）
+//	参数：name、x.name或{x.name}，可以使用.访问结构体字段或map的值，使用[]访问slice元素
+//	函数：empty(x) len(x) length(x) size(x) contains(x, y) startsWith(x, y) endsWith(x, y) trim(x) upper(x) lower(x)，
+//	也可以使用方法的形式调用，如x.size()、x.isEmpty()
+//
+// 与nil比较时，空字符串及零值时间也被认为是nil
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile 编译表达式
+func Compile(src string) (*Expr, error) {
+	root, err := parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("compile expression [%s] failed: %v", src, err)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval 计算表达式的值
+func (e *Expr) Eval(resolve Resolver) (interface{}, error) {
+	return eval(e.root, resolve)
+}
+
+// EvalBool 计算表达式并转换为bool
+func (e *Expr) EvalBool(resolve Resolver) (bool, error) {
+	v, err := e.Eval(resolve)
+	if err != nil {
+		return false, err
+	}
+	return Truthy(v), nil
+}
+
+// Truthy 将值转换为bool：nil、false、0、空字符串及空集合为false
+func Truthy(v interface{}) bool {
+	v = indirect(v)
+	if v == nil {
+		return false
+	}
+	switch x := v.(type) {
+	case bool:
+		return x
+	case string:
+		return x != ""
+	case time.Time:
+		return !x.IsZero()
+	}
+	if f, ok := toNumber(v); ok {
+		return toFloat(f) != 0
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() > 0
+	}
+	return true
+}
+
+func eval(n node, resolve Resolver) (interface{}, error) {
+	switch x := n.(type) {
+	case *literalNode:
+		return x.value, nil
+	case *varNode:
+		return resolveVar(strings.Join(x.parts, "."), resolve), nil
+	case *fieldNode:
+		v, err := eval(x.x, resolve)
+		if err != nil {
+			return nil, err
+		}
+		return field(v, x.name), nil
+	case *indexNode:
+		v, err := eval(x.x, resolve)
+		if err != nil {
+			return nil, err
+		}
+		idx, err := eval(x.index, resolve)
+		if err != nil {
+			return nil, err
+		}
+		return index(v, idx), nil
+	case *unaryNode:
+		v, err := eval(x.x, resolve)
+		if err != nil {
+			return nil, err
+		}
+		if x.op == "!" {
+			return !Truthy(v), nil
+		}
+		f, ok := toNumber(indirect(v))
+		if !ok {
+			return nil, fmt.Errorf("operator - not support %v", v)
+		}
+		if i, ok := f.(int64); ok {
+			return -i, nil
+		}
+		return -f.(float64), nil
+	case *binaryNode:
+		return evalBinary(x, resolve)
+	case *callNode:
+		args := make([]interface{}, len(x.args))
+		for i := range x.args {
+			v, err := eval(x.args[i], resolve)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = indirect(v)
+		}
+		return call(x.name, args)
+	}
+	return nil, fmt.Errorf("unknown expression node %T", n)
+}
+
+func evalBinary(x *binaryNode, resolve Resolver) (interface{}, error) {
+	l, err := eval(x.l, resolve)
+	if err != nil {
+		return nil, err
+	}
+	switch x.op {
+	case "&&":
+		if !Truthy(l) {
+			return false, nil
+		}
+		r, err := eval(x.r, resolve)
+		return Truthy(r), err
+	case "||":
+		if Truthy(l) {
+			return true, nil
+		}
+		r, err := eval(x.r, resolve)
+		return Truthy(r), err
+	}
+
+	r, err := eval(x.r, resolve)
+	if err != nil {
+		return nil, err
+	}
+	l, r = indirect(l), indirect(r)
+	switch x.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "<", ">", "<=", ">=":
+		if isNil(l) || isNil(r) {
+			return false, nil
+		}
+		c, err := compare(l, r)
+		if err != nil {
+			return nil, err
+		}
+		switch x.op {
+		case "<":
+			return c < 0, nil
+		case ">":
+			return c > 0, nil
+		case "<=":
+			return c <= 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case "+":
+		_, ls := l.(string)
+		_, rs := r.(string)
+		if ls || rs {
+			return toString(l) + toString(r), nil
+		}
+	}
+	return arithmetic(x.op, l, r)
+}
+
+// resolveVar 优先使用完整的名称获得参数，不存在时使用最长的前缀获得参数并按路径访问字段
+func resolveVar(name string, resolve Resolver) interface{} {
+	if v, ok := resolve(name); ok {
+		return v
+	}
+	parts := strings.Split(name, ".")
+	for i := len(parts) - 1; i > 0; i-- {
+		if v, ok := resolve(strings.Join(parts[:i], ".")); ok {
+			for _, p := range parts[i:] {
+				v = field(v, p)
+			}
+			return v
+		}
+	}
+	return nil
+}
+
+func field(v interface{}, name string) interface{} {
+	v = indirect(v)
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Struct:
+		f := rv.FieldByName(name)
+		if !f.IsValid() {
+			//兼容column tag名称
+			rt := rv.Type()
+			for i := 0; i < rt.NumField(); i++ {
+				if rt.Field(i).Tag.Get("column") == name || strings.EqualFold(rt.Field(i).Name, name) {
+					f = rv.Field(i)
+					break
+				}
+			}
+		}
+		if f.IsValid() && f.CanInterface() {
+			return f.Interface()
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			mv := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+			if mv.IsValid() {
+				return mv.Interface()
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < rv.Len() {
+			return rv.Index(i).Interface()
+		}
+	}
+	return nil
+}
+
+func index(v interface{}, idx interface{}) interface{} {
+	idx = indirect(idx)
+	if n, ok := toNumber(idx); ok {
+		if i, ok := n.(int64); ok {
+			return field(v, strconv.FormatInt(i, 10))
+		}
+	}
+	return field(v, toString(idx))
+}
+
+// indirect 获得指针指向的值，nil指针返回nil
+func indirect(v interface{}) interface{} {
+	if rv, ok := v.(reflect.Value); ok {
+		if !rv.IsValid() || !rv.CanInterface() {
+			return nil
+		}
+		v = rv.Interface()
+	}
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	return rv.Interface()
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch x := v.(type) {
+	case string:
+		return x == ""
+	case time.Time:
+		return x.IsZero()
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func equal(l, r interface{}) bool {
+	if isNil(l) || isNil(r) {
+		return isNil(l) && isNil(r)
+	}
+	if c, err := compare(l, r); err == nil {
+		return c == 0
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+// compare 比较两个值：数字按数值，时间按先后，其他按字符串比较
+func compare(l, r interface{}) (int, error) {
+	if lt, ok := l.(time.Time); ok {
+		if rt, ok := r.(time.Time); ok {
+			switch {
+			case lt.Before(rt):
+				return -1, nil
+			case lt.After(rt):
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	ln, lok := toNumber(l)
+	rn, rok := toNumber(r)
+	if lok && rok {
+		if c, ok := compareInt(ln, rn); ok {
+			return c, nil
+		}
+		return compareOrdered(toFloat(ln), toFloat(rn)), nil
+	}
+	if bl, ok := l.(bool); ok {
+		if br, ok := r.(bool); ok {
+			if bl == br {
+				return 0, nil
+			}
+			return 1, nil
+		}
+	}
+	if isComparableKind(l) && isComparableKind(r) {
+		return strings.Compare(toString(l), toString(r)), nil
+	}
+	return 0, fmt.Errorf("cannot compare %v with %v", l, r)
+}
+
+// compareInt 比较两个整数，大于math.MaxInt64的无符号整数保存为uint64，任意一个为float64时返回false
+func compareInt(l, r interface{}) (int, bool) {
+	switch lv := l.(type) {
+	case int64:
+		switch rv := r.(type) {
+		case int64:
+			return compareOrdered(lv, rv), true
+		case uint64:
+			return -1, true
+		}
+	case uint64:
+		switch rv := r.(type) {
+		case int64:
+			return 1, true
+		case uint64:
+			return compareOrdered(lv, rv), true
+		}
+	}
+	return 0, false
+}
+
+func compareOrdered[T int64 | uint64 | float64](l, r T) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+func isComparableKind(v interface{}) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func arithmetic(op string, l, r interface{}) (interface{}, error) {
+	ln, lok := toNumber(l)
+	rn, rok := toNumber(r)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operator %s not support %v and %v", op, l, r)
+	}
+	li, lint := ln.(int64)
+	ri, rint := rn.(int64)
+	if lint && rint {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		case "/", "%":
+			if ri == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if op == "/" {
+				return li / ri, nil
+			}
+			return li % ri, nil
+		}
+	}
+	lf, rf := toFloat(ln), toFloat(rn)
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return lf / rf, nil
+	}
+	return nil, fmt.Errorf("operator %s not support float", op)
+}
+
+// toNumber 将数字或数字字符串转换为int64或float64，超出int64范围的无符号整数转换为uint64
+func toNumber(v interface{}) (interface{}, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fromUint(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		s := strings.TrimSpace(rv.String())
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, true
+		}
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return fromUint(u), true
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func fromUint(u uint64) interface{} {
+	if u > math.MaxInt64 {
+		return u
+	}
+	return int64(u)
+}
+
+func toFloat(n interface{}) float64 {
+	switch x := n.(type) {
+	case int64:
+		return float64(x)
+	case uint64:
+		return float64(x)
+	}
+	return n.(float64)
+}
+
+func toString(v interface{}) string {
+	v = indirect(v)
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	}
+	return fmt.Sprint(v)
+}
+
+func length(v interface{}) (int64, error) {
+	if v == nil {
+		return 0, nil
+	}
+	if s, ok := v.(string); ok {
+		return int64(len([]rune(s))), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return int64(rv.Len()), nil
+	}
+	return 0, fmt.Errorf("len not support %T", v)
+}
+
+func call(name string, args []interface{}) (interface{}, error) {
+	argc := map[string]int{
+		"empty": 1, "isEmpty": 1, "len": 1, "length": 1, "size": 1,
+		"trim": 1, "upper": 1, "lower": 1, "toUpperCase": 1, "toLowerCase": 1,
+		"contains": 2, "startsWith": 2, "endsWith": 2,
+	}
+	n, ok := argc[name]
+	if !ok {
+		return nil, fmt.Errorf("function %s not support", name)
+	}
+	if len(args) != n {
+		return nil, fmt.Errorf("function %s expect %d arguments, get %d", name, n, len(args))
+	}
+	switch name {
+	case "empty", "isEmpty":
+		if isNil(args[0]) {
+			return true, nil
+		}
+		l, err := length(args[0])
+		if err != nil {
+			return false, nil
+		}
+		return l == 0, nil
+	case "len", "length", "size":
+		return length(args[0])
+	case "trim":
+		return strings.TrimSpace(toString(args[0])), nil
+	case "upper", "toUpperCase":
+		return strings.ToUpper(toString(args[0])), nil
+	case "lower", "toLowerCase":
+		return strings.ToLower(toString(args[0])), nil
+	case "contains":
+		return contains(args[0], args[1]), nil
+	case "startsWith":
+		return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+	case "endsWith":
+		return strings.HasSuffix(toString(args[0]), toString(args[1])), nil
+	}
+	return nil, fmt.Errorf("function %s not support", name)
+}
+
+// contains 字符串是否包含子串，集合是否包含元素，map是否包含key
+func contains(v, elem interface{}) bool {
+	if s, ok := v.(string); ok {
+		return strings.Contains(s, toString(elem))
+	}
+	if v == nil {
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if equal(indirect(rv.Index(i).Interface()), elem) {
+				return true
+			}
+		}
+	case reflect.Map:
+		for _, k := range rv.MapKeys() {
+			if equal(k.Interface(), elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package expr
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func evalBool(t *testing.T, src string, params map[string]interface{}) bool {
+	e, err := Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret, err := e.EvalBool(func(name string) (interface{}, bool) {
+		v, ok := params[name]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatalf("eval [%s] failed: %v", src, err)
+	}
+	return ret
+}
+
+func TestCompareInt(t *testing.T) {
+	params := map[string]interface{}{
+		"min":     int64(math.MinInt64 + 1),
+		"max":     int64(math.MaxInt64 - 1),
+		"umax":    uint64(math.MaxUint64),
+		"ubig":    uint64(math.MaxUint64 - 1),
+		"uint":    uint8(3),
+		"float":   2.5,
+		"numText": "18446744073709551615",
+	}
+	cases := []struct {
+		src    string
+		expect bool
+	}{
+		{"min > max", false},
+		{"min < max", true},
+		{"-9223372036854775807 > 9223372036854775806", false},
+		{"umax > max", true},
+		{"umax > ubig", true},
+		{"ubig < umax", true},
+		{"umax == numText", true},
+		{"min < umax", true},
+		{"uint > float", true},
+		{"uint == 3", true},
+	}
+	for _, c := range cases {
+		if ret := evalBool(t, c.src, params); ret != c.expect {
+			t.Fatalf("[%s] expect %v, get %v", c.src, c.expect, ret)
+		}
+	}
+}
+
+func TestTruthy(t *testing.T) {
+	var nilPtr *int
+	zero := 0
+	cases := []struct {
+		v      interface{}
+		expect bool
+	}{
+		{nil, false},
+		{0, false},
+		{int64(0), false},
+		{uint(0), false},
+		{0.0, false},
+		{"", false},
+		{false, false},
+		{time.Time{}, false},
+		{[]int{}, false},
+		{nilPtr, false},
+		{&zero, false},
+		{1, true},
+		{uint64(math.MaxUint64), true},
+		{-0.5, true},
+		{"a", true},
+		{true, true},
+		{[]int{1}, true},
+		{struct{}{}, true},
+	}
+	for _, c := range cases {
+		if ret := Truthy(c.v); ret != c.expect {
+			t.Fatalf("Truthy(%#v) expect %v, get %v", c.v, c.expect, ret)
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	params := map[string]interface{}{
+		"x": struct {
+			Name string
+			Age  int
+		}{Name: " a ", Age: 0},
+		"list": []string{"a", "b"},
+	}
+	cases := []struct {
+		src    string
+		expect bool
+	}{
+		{"x.Age", false},
+		{"!x.Age && x.Name != nil", true},
+		{"x.Age + 1 == 1 and x.Age - 1 lt 0", true},
+		{"x.Name.trim() == 'a'", true},
+		{"list.size() == 2 and contains(list, 'b') and list[0] == 'a'", true},
+		{"missing == nil", true},
+	}
+	for _, c := range cases {
+		if ret := evalBool(t, c.src, params); ret != c.expect {
+			t.Fatalf("[%s] expect %v, get %v", c.src, c.expect, ret)
+		}
+	}
+	if _, err := Compile("x >"); err == nil {
+		t.Fatal("expect compile error")
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	// tokenVar 使用{}包裹的参数名，如{TestTable.id}、{0}、#{id}
+	tokenVar
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// 按长度优先匹配的操作符
+var gOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", ",", "+", "-", "*", "/", "%", "[", "]", "."}
+
+// 作为操作符使用的关键字
+var gWordOperators = map[string]string{
+	"and": "&&",
+	"or":  "||",
+	"not": "!",
+	"eq":  "==",
+	"neq": "!=",
+	"lt":  "<",
+	"gt":  ">",
+	"lte": "<=",
+	"gte": ">=",
+}
+
+func tokenize(src string) ([]token, error) {
+	var ret []token
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			buf := strings.Builder{}
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				buf.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			ret = append(ret, token{kind: tokenString, value: buf.String(), pos: i})
+			i = j + 1
+		case r == '{' || (r == '#' && i+1 < len(runes) && runes[i+1] == '{'):
+			//兼容#{name}的写法
+			if r == '#' {
+				i++
+			}
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '}'; j++ {
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated var at %d", i)
+			}
+			ret = append(ret, token{kind: tokenVar, value: strings.TrimSpace(string(runes[i+1 : j])), pos: i})
+			i = j + 1
+		case unicode.IsDigit(r):
+			j := i
+			for ; j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.'); j++ {
+			}
+			ret = append(ret, token{kind: tokenNumber, value: string(runes[i:j]), pos: i})
+			i = j
+		case r == '_' || unicode.IsLetter(r):
+			j := i
+			for ; j < len(runes) && (runes[j] == '_' || unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])); j++ {
+			}
+			word := string(runes[i:j])
+			if op, ok := gWordOperators[word]; ok {
+				ret = append(ret, token{kind: tokenOperator, value: op, pos: i})
+			} else {
+				ret = append(ret, token{kind: tokenIdent, value: word, pos: i})
+			}
+			i = j
+		default:
+			matched := false
+			for _, op := range gOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					ret = append(ret, token{kind: tokenOperator, value: op, pos: i})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character '%c' at %d", r, i)
+			}
+		}
+	}
+	return append(ret, token{kind: tokenEOF, pos: len(runes)}), nil
+}
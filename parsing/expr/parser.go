@@ -0,0 +1,300 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package expr
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type node interface{}
+
+type literalNode struct {
+	value interface{}
+}
+
+// varNode 参数，parts为使用.分隔的路径，如TestTable.username
+type varNode struct {
+	parts []string
+}
+
+type fieldNode struct {
+	x    node
+	name string
+}
+
+type indexNode struct {
+	x     node
+	index node
+}
+
+type unaryNode struct {
+	op string
+	x  node
+}
+
+type binaryNode struct {
+	op   string
+	l, r node
+}
+
+// callNode 函数调用，方法调用时接收者作为第一个参数
+type callNode struct {
+	name string
+	args []node
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && t.value == op
+}
+
+func (p *parser) expect(op string) error {
+	if !p.isOp(op) {
+		return p.errorf("expect '%s'", op)
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at %d", fmt.Sprintf(format, args...), p.peek().pos)
+}
+
+func parse(src string) (node, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	ret, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.errorf("unexpected token '%s'", p.peek().value)
+	}
+	return ret, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseBinary(p.parseNot, "&&")
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isOp("!") {
+		p.next()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "!", x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	l, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.isOp(op) {
+			p.next()
+			r, err := p.parseAdd()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryNode{op: op, l: l, r: r}, nil
+		}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAdd() (node, error) {
+	return p.parseBinary(p.parseMul, "+", "-")
+}
+
+func (p *parser) parseMul() (node, error) {
+	return p.parseBinary(p.parseUnary, "*", "/", "%")
+}
+
+func (p *parser) parseBinary(operand func() (node, error), ops ...string) (node, error) {
+	l, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		matched := ""
+		for _, op := range ops {
+			if p.isOp(op) {
+				matched = op
+				break
+			}
+		}
+		if matched == "" {
+			return l, nil
+		}
+		p.next()
+		r, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{op: matched, l: l, r: r}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("-") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: "-", x: x}, nil
+	}
+	if p.isOp("!") {
+		return p.parseNot()
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.isOp("."):
+			p.next()
+			t := p.next()
+			if t.kind != tokenIdent && t.kind != tokenNumber {
+				return nil, p.errorf("expect name after '.'")
+			}
+			if p.isOp("(") {
+				args, err := p.parseArgs()
+				if err != nil {
+					return nil, err
+				}
+				x = &callNode{name: t.value, args: append([]node{x}, args...)}
+			} else if v, ok := x.(*varNode); ok {
+				v.parts = append(v.parts, t.value)
+			} else {
+				x = &fieldNode{x: x, name: t.value}
+			}
+		case p.isOp("["):
+			p.next()
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &indexNode{x: x, index: index}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *parser) parseArgs() ([]node, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []node
+	for !p.isOp(")") {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	return args, p.expect(")")
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		if i, err := strconv.ParseInt(t.value, 10, 64); err == nil {
+			return &literalNode{value: i}, nil
+		}
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at %d", t.value, t.pos)
+		}
+		return &literalNode{value: f}, nil
+	case tokenString:
+		return &literalNode{value: t.value}, nil
+	case tokenVar:
+		return &varNode{parts: []string{t.value}}, nil
+	case tokenIdent:
+		switch t.value {
+		case "nil", "null":
+			return &literalNode{value: nil}, nil
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+		if p.isOp("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			return &callNode{name: t.value, args: args}, nil
+		}
+		return &varNode{parts: []string{t.value}}, nil
+	case tokenOperator:
+		if t.value == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		}
+	}
+	return nil, fmt.Errorf("unexpected token '%s' at %d", t.value, t.pos)
+}
@@ -0,0 +1,141 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsing
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acmestack/gobatis/cache"
+	"github.com/acmestack/gobatis/reflection"
+)
+
+// Statement mapper文件中语句的配置
+type Statement struct {
+	// Id 包含namespace的完整id
+	Id string
+	// Namespace 所属的namespace
+	Namespace string
+	// Action 语句类型：select、insert、update、delete
+	Action string
+	// UseCache 是否使用namespace的二级缓存，仅对select有效
+	UseCache bool
+	// FlushCache 执行语句后是否清空namespace的二级缓存
+	FlushCache bool
+	// Cache namespace的二级缓存，未配置<cache>时为nil
+	Cache cache.Cache
+	// Timeout 语句执行的超时时间，大于0时作为执行context的deadline
+	Timeout time.Duration
+	// ResultMap select使用的resultMap，未配置时为nil
+	ResultMap *reflection.ResultMap
+	// FetchSize 每次从数据库获取的行数提示，仅对select有效，大于0时通过context传递给执行器及驱动
+	FetchSize int
+	// UseGeneratedKeys 是否将数据库生成的主键写回参数，仅对insert有效
+	UseGeneratedKeys bool
+	// KeyProperty 主键写回的参数字段
+	KeyProperty []string
+	// KeyColumn 生成主键的列，使用RETURNING获得主键时使用，为空时与KeyProperty相同
+	KeyColumn []string
+	// SelectKey 获得主键的语句，未配置<selectKey>时为nil
+	SelectKey *SelectKey
+}
+
+const (
+	// SelectKeyBefore 在语句执行前获得主键
+	SelectKeyBefore = "BEFORE"
+	// SelectKeyAfter 在语句执行后获得主键
+	SelectKeyAfter = "AFTER"
+)
+
+// SelectKey <selectKey>的配置
+type SelectKey struct {
+	// Order SelectKeyBefore或SelectKeyAfter
+	Order string
+	// KeyProperty 结果写回的参数字段
+	KeyProperty []string
+	// KeyColumn 结果中对应KeyProperty的列，为空时按顺序使用结果的列
+	KeyColumn []string
+	// Sql 获得主键的语句
+	Sql *DynamicData
+}
+
+// NewStatement 创建语句配置，select默认使用缓存且不清空缓存，其他语句默认清空缓存
+func NewStatement(namespace, id, action, useCache, flushCache string) *Statement {
+	isSelect := action == "select"
+	return &Statement{
+		Id:         id,
+		Namespace:  namespace,
+		Action:     action,
+		UseCache:   parseBool(useCache, isSelect),
+		FlushCache: parseBool(flushCache, !isSelect),
+	}
+}
+
+func parseBool(s string, defaultValue bool) bool {
+	if b, err := strconv.ParseBool(s); err == nil {
+		return b
+	}
+	return defaultValue
+}
+
+// ParseTimeout 解析timeout属性，整数表示秒，也支持"500ms"等带单位的时间
+// 未设置时返回0及true，无法解析或为负数时返回0及false
+func ParseTimeout(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, true
+	}
+	d, err := time.ParseDuration(s)
+	if i, e := strconv.Atoi(s); e == nil {
+		d, err = time.Duration(i)*time.Second, nil
+	}
+	if err != nil || d < 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// ParseFetchSize 解析fetchSize属性，未设置时返回0及true，无法解析或小于等于0时返回0及false
+func ParseFetchSize(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, true
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil || i <= 0 {
+		return 0, false
+	}
+	return i, true
+}
+
+// ParseNames 解析使用逗号分隔的keyProperty、keyColumn等属性
+func ParseNames(s string) []string {
+	var ret []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// ParseBool 解析bool属性，无法解析时返回defaultValue
+func ParseBool(s string, defaultValue bool) bool {
+	return parseBool(strings.TrimSpace(s), defaultValue)
+}
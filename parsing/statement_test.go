@@ -0,0 +1,64 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package parsing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeout(t *testing.T) {
+	cases := []struct {
+		s  string
+		d  time.Duration
+		ok bool
+	}{
+		{"", 0, true},
+		{" 3 ", 3 * time.Second, true},
+		{"500ms", 500 * time.Millisecond, true},
+		{"abc", 0, false},
+		{"-1", 0, false},
+		{"-1s", 0, false},
+	}
+	for _, c := range cases {
+		d, ok := ParseTimeout(c.s)
+		if d != c.d || ok != c.ok {
+			t.Fatalf("ParseTimeout(%q) expect %v %v, get %v %v", c.s, c.d, c.ok, d, ok)
+		}
+	}
+}
+
+func TestParseFetchSize(t *testing.T) {
+	cases := []struct {
+		s    string
+		size int
+		ok   bool
+	}{
+		{"", 0, true},
+		{" 500 ", 500, true},
+		{"0", 0, false},
+		{"-1", 0, false},
+		{"abc", 0, false},
+	}
+	for _, c := range cases {
+		size, ok := ParseFetchSize(c.s)
+		if size != c.size || ok != c.ok {
+			t.Fatalf("ParseFetchSize(%q) expect %v %v, get %v %v", c.s, c.size, c.ok, size, ok)
+		}
+	}
+}
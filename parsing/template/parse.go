@@ -18,6 +18,7 @@
 package template
 
 import (
+	"io/fs"
 	"io/ioutil"
 	"strings"
 	"sync"
@@ -78,12 +79,15 @@ func (p *Parser) ParseMetadata(driverName string, params ...interface{}) (*sqlpa
 
 type Manager struct {
 	sqlMap map[string]*Parser
-	lock   sync.Mutex
+	// 每个模板文件注册的语句，用于重新加载
+	sources map[string][]string
+	lock    sync.Mutex
 }
 
 func NewManager() *Manager {
 	return &Manager{
-		sqlMap: map[string]*Parser{},
+		sqlMap:  map[string]*Parser{},
+		sources: map[string][]string{},
 	}
 }
 
@@ -91,22 +95,12 @@ func (manager *Manager) RegisterData(data []byte) error {
 	manager.lock.Lock()
 	defer manager.lock.Unlock()
 
-	tpl := template.New("")
-	tpl = tpl.Funcs(dummyFuncMap)
-	tpl, err := tpl.Parse(string(data))
+	tpl, err := parseTemplate(data)
 	if err != nil {
 		logging.Warn("register template data failed: %s err: %v\n", string(data), err)
 		return err
 	}
-
-	ns := getNamespace(tpl)
-	tpls := tpl.Templates()
-	for _, v := range tpls {
-		if v.Name() != "" && v.Name() != namespaceTmplName {
-			manager.sqlMap[ns+v.Name()] = &Parser{tpl: v}
-		}
-	}
-
+	manager.register("", tpl)
 	return nil
 }
 
@@ -114,28 +108,83 @@ func (manager *Manager) RegisterFile(file string) error {
 	manager.lock.Lock()
 	defer manager.lock.Unlock()
 
-	tpl := template.New("")
 	data, err := ioutil.ReadFile(file)
 	if err != nil {
 		logging.Warn("register template file failed: %s err: %v\n", file, err)
 		return err
 	}
-	tpl = tpl.Funcs(dummyFuncMap)
-	tpl, err = tpl.Parse(string(data))
+	tpl, err := parseTemplate(data)
 	if err != nil {
 		logging.Warn("register template file failed: %s err: %v\n", file, err)
 		return err
 	}
+	manager.register(file, tpl)
+	return nil
+}
 
+// RegisterFileFS 注册fsys中的模板文件，可以用于embed.FS
+func (manager *Manager) RegisterFileFS(fsys fs.FS, file string) error {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		logging.Warn("register template file failed: %s err: %v\n", file, err)
+		return err
+	}
+	tpl, err := parseTemplate(data)
+	if err != nil {
+		logging.Warn("register template file failed: %s err: %v\n", file, err)
+		return err
+	}
+
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	manager.register(file, tpl)
+	return nil
+}
+
+// ReloadFile 重新加载模板文件，使用新的语句替换该文件之前注册的语句
+// 文件存在错误时返回错误并继续使用之前的版本
+func (manager *Manager) ReloadFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		logging.Warn("reload template file failed: %s err: %v\n", file, err)
+		return err
+	}
+	tpl, err := parseTemplate(data)
+	if err != nil {
+		logging.Warn("reload template file failed: %s err: %v\n", file, err)
+		return err
+	}
+
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	for _, k := range manager.sources[file] {
+		delete(manager.sqlMap, k)
+	}
+	manager.register(file, tpl)
+	return nil
+}
+
+func parseTemplate(data []byte) (*template.Template, error) {
+	tpl := template.New("")
+	tpl = tpl.Funcs(dummyFuncMap)
+	return tpl.Parse(string(data))
+}
+
+func (manager *Manager) register(file string, tpl *template.Template) {
 	ns := getNamespace(tpl)
 	tpls := tpl.Templates()
+	var keys []string
 	for _, v := range tpls {
 		if v.Name() != "" && v.Name() != namespaceTmplName {
 			manager.sqlMap[ns+v.Name()] = &Parser{tpl: v}
+			keys = append(keys, ns+v.Name())
 		}
 	}
-
-	return nil
+	if file != "" {
+		manager.sources[file] = keys
+	}
 }
 
 func getNamespace(tpl *template.Template) string {
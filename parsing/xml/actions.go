@@ -56,6 +56,8 @@ type Insert struct {
 	//Where   Where   `xml:"where"`
 	//Data    string  `xml:",chardata"`
 	Data string `xml:",innerxml"`
+
+	SelectKey *SelectKey `xml:"selectKey"`
 }
 
 type Update struct {
@@ -72,6 +74,8 @@ type Update struct {
 	//Where   Where   `xml:"where"`
 	//Data    string  `xml:",chardata"`
 	Data string `xml:",innerxml"`
+
+	SelectKey *SelectKey `xml:"selectKey"`
 }
 
 type Delete struct {
@@ -89,6 +93,15 @@ type Delete struct {
 	Data string `xml:",innerxml"`
 }
 
+// SelectKey 获得主键的语句，order为BEFORE时在语句执行前执行，AFTER时在语句执行后执行
+type SelectKey struct {
+	KeyProperty string `xml:"keyProperty,attr"`
+	KeyColumn   string `xml:"keyColumn,attr"`
+	Order       string `xml:"order,attr"`
+	ResultType  string `xml:"resultType,attr"`
+	Data        string `xml:",innerxml"`
+}
+
 func (a *Select) ParseDynamic() {
 
 }
@@ -0,0 +1,63 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xml
+
+import (
+	"github.com/acmestack/gobatis/logging"
+	"github.com/acmestack/gobatis/parsing"
+	"github.com/acmestack/gobatis/parsing/expr"
+)
+
+// Bind 计算表达式并将结果以name绑定到所在的作用域中，之后的元素可以在#{name}及test中使用
+// value为表达式，语法与test相同，如：
+// <bind name="pattern" value="'%' + {name} + '%'"/>
+// 在<if>中时只有条件成立才绑定；在<foreach>中时每次迭代分别计算，可以使用item及index
+type Bind struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+	//编译后的value表达式
+	expr *expr.Expr
+}
+
+//传入方法必须是通过参数名获得参数值
+func (de *Bind) Format(getFunc func(key string) string) string {
+	return de.FormatValue(parsing.NewValueContext(parsing.ValueGetter(getFunc)))
+}
+
+// FormatValue 在当前作用域中计算表达式并绑定结果，bind元素本身不输出sql
+func (de *Bind) FormatValue(ctx *parsing.DynamicContext) string {
+	if de.Name == "" {
+		return ""
+	}
+	e := de.expr
+	if e == nil {
+		var err error
+		e, err = expr.Compile(de.Value)
+		if err != nil {
+			logging.Warn("%v\n", err)
+			return ""
+		}
+	}
+	v, err := e.Eval(ctx.Value)
+	if err != nil {
+		logging.Warn("eval bind [%s] failed: %v\n", de.Value, err)
+		return ""
+	}
+	ctx.BindVar(de.Name, v)
+	return ""
+}
@@ -0,0 +1,246 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xml
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/logging"
+	"github.com/acmestack/gobatis/parsing"
+	"github.com/acmestack/gobatis/parsing/expr"
+)
+
+// 包裹语句内容的根元素，使语句可以作为完整的xml解析
+const dynamicRoot = "dynamic"
+
+type dynamicParser struct {
+	data *parsing.DynamicData
+	//查找include引用的sql片段
+	finder fragmentFinder
+	//语句所在的namespace
+	namespace string
+	//解析sql片段时的片段链，用于检查循环引用
+	chain []string
+}
+
+// ParseDynamic 将语句内容解析为文本及动态元素组成的节点树，元素可以任意嵌套
+// 语句内容需要是合法的xml，<、&等字符需要转义或使用CDATA
+// include在sqls中查找引用的sql片段
+func ParseDynamic(src string, sqls []Sql) (*parsing.DynamicData, error) {
+	return parseDynamic(src, "", func(namespace, refid string) (*Sql, bool) {
+		return findSql(sqls, namespace, refid)
+	})
+}
+
+func parseDynamic(src, namespace string, finder fragmentFinder) (*parsing.DynamicData, error) {
+	ret := &parsing.DynamicData{OriginData: src}
+	p := &dynamicParser{data: ret, finder: finder, namespace: namespace}
+	contents, err := p.parse(src)
+	if err != nil {
+		logging.Warn("parse dynamic sql failed: %v, sql: %s\n", err, src)
+		return nil, errors.ParseDynamicSqlError
+	}
+	ret.Elements = contents
+	return ret, nil
+}
+
+func (p *dynamicParser) parse(src string) ([]parsing.DynamicElement, error) {
+	d := xml.NewDecoder(strings.NewReader("<" + dynamicRoot + ">" + src + "</" + dynamicRoot + ">"))
+	if _, err := d.Token(); err != nil {
+		return nil, err
+	}
+	return p.parseContents(d)
+}
+
+// parseContents 按顺序解析文本及动态元素，直到当前元素结束
+func (p *dynamicParser) parseContents(d *xml.Decoder) ([]parsing.DynamicElement, error) {
+	var ret []parsing.DynamicElement
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return ret, err
+		}
+		switch t := token.(type) {
+		case xml.CharData:
+			if s := strings.TrimSpace(string(t)); s != "" {
+				ret = append(ret, Text(s))
+			}
+		case xml.StartElement:
+			de, err := p.parseElement(d, t)
+			if err != nil {
+				return ret, err
+			}
+			if de != nil {
+				ret = append(ret, de)
+			}
+		case xml.EndElement:
+			return ret, nil
+		}
+	}
+}
+
+// parseElement 解析动态元素，不支持的元素打印警告并忽略
+func (p *dynamicParser) parseElement(d *xml.Decoder, start xml.StartElement) (parsing.DynamicElement, error) {
+	logging.Debug("Found element : %s\n", start.Name.Local)
+	var err error
+	switch start.Name.Local {
+	case "if":
+		v := &If{}
+		err = p.parseIf(d, start, v)
+		return v, err
+	case "choose":
+		return p.parseChoose(d)
+	case "foreach":
+		v := &Foreach{
+			Item:       attr(start, "item"),
+			Collection: attr(start, "collection"),
+			Separator:  attr(start, "separator"),
+			Index:      attr(start, "index"),
+			Open:       attr(start, "open"),
+			Close:      attr(start, "close"),
+		}
+		if v.collection, err = expr.Compile(v.Collection); err != nil {
+			logging.Warn("%v\n", err)
+		}
+		v.Contents, err = p.parseContents(d)
+		return v, err
+	case "trim":
+		v := &Trim{
+			Prefix:          attr(start, "prefix"),
+			Suffix:          attr(start, "suffix"),
+			PrefixOverrides: attr(start, "prefixOverrides"),
+			SuffixOverrides: attr(start, "suffixOverrides"),
+		}
+		v.Contents, err = p.parseContents(d)
+		return v, err
+	case "where":
+		v := &Where{}
+		v.Contents, err = p.parseContents(d)
+		return v, err
+	case "set":
+		v := &Set{}
+		v.Contents, err = p.parseContents(d)
+		return v, err
+	case "include":
+		return p.parseInclude(d, start)
+	case "selectKey":
+		//作为语句的配置解析
+		return nil, d.Skip()
+	case "bind":
+		v := &Bind{Name: attr(start, "name"), Value: attr(start, "value")}
+		if v.expr, err = expr.Compile(v.Value); err != nil {
+			logging.Warn("%v\n", err)
+		}
+		return v, d.Skip()
+	}
+	logging.Warn("element not support: %s\n", start.Name.Local)
+	return nil, d.Skip()
+}
+
+func (p *dynamicParser) parseIf(d *xml.Decoder, start xml.StartElement, v *If) error {
+	v.Test = attr(start, "test")
+	e, err := expr.Compile(v.Test)
+	if err != nil {
+		logging.Warn("%v\n", err)
+	} else {
+		v.expr = e
+	}
+	v.Contents, err = p.parseContents(d)
+	return err
+}
+
+func (p *dynamicParser) parseChoose(d *xml.Decoder) (parsing.DynamicElement, error) {
+	v := &Choose{}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return v, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "when":
+				when := When{}
+				if err := p.parseIf(d, t, &when.If); err != nil {
+					return v, err
+				}
+				v.When = append(v.When, when)
+			case "otherwise":
+				contents, err := p.parseContents(d)
+				if err != nil {
+					return v, err
+				}
+				v.Otherwise.Contents = contents
+			default:
+				logging.Warn("element not support in choose: %s\n", t.Name.Local)
+				if err := d.Skip(); err != nil {
+					return v, err
+				}
+			}
+		case xml.EndElement:
+			return v, nil
+		}
+	}
+}
+
+func (p *dynamicParser) parseInclude(d *xml.Decoder, start xml.StartElement) (parsing.DynamicElement, error) {
+	v := &Include{
+		Refid:     attr(start, "refid"),
+		namespace: p.namespace,
+		finder:    p.finder,
+		chain:     p.chain,
+	}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return v, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "property" {
+				v.Properties = append(v.Properties, Property{Name: attr(t, "name"), Value: attr(t, "value")})
+			}
+			if err := d.Skip(); err != nil {
+				return v, err
+			}
+		case xml.EndElement:
+			return v, nil
+		}
+	}
+}
+
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// findSql 在sqls中查找id为refid的sql片段，refid可以包含namespace
+func findSql(sqls []Sql, namespace, refid string) (*Sql, bool) {
+	for i := range sqls {
+		if refid == sqls[i].Id || (namespace != "" && refid == namespace+"."+sqls[i].Id) {
+			return &sqls[i], true
+		}
+	}
+	return nil, false
+}
@@ -18,13 +18,13 @@
 package xml
 
 import (
-	"encoding/xml"
-	"fmt"
-	"github.com/acmestack/gobatis/logging"
-	"github.com/acmestack/gobatis/parsing"
-	"strconv"
 	"strings"
+	"sync"
 	"unicode"
+
+	"github.com/acmestack/gobatis/logging"
+	"github.com/acmestack/gobatis/parsing"
+	"github.com/acmestack/gobatis/parsing/expr"
 )
 
 type Foreach struct {
@@ -34,12 +34,19 @@ type Foreach struct {
 	Index      string `xml:"index,attr"`
 	Open       string `xml:"open,attr"`
 	Close      string `xml:"close,attr"`
-	Data       string `xml:",chardata"`
+	//按顺序保存的文本及动态元素
+	Contents []parsing.DynamicElement `xml:"-"`
+	//编译后的collection表达式
+	collection *expr.Expr
 }
 
 type Sql struct {
 	Id  string `xml:"id,attr"`
 	Sql string `xml:",chardata"`
+	//片段的原始内容，可以包含动态元素及include
+	Data string `xml:",innerxml"`
+	//片段所属的namespace
+	namespace string
 }
 
 type Property struct {
@@ -50,30 +57,58 @@ type Property struct {
 type Include struct {
 	Refid      string     `xml:"refid,attr"`
 	Properties []Property `xml:"property"`
-	Sql        Sql        `xml:-`
+	Sql        Sql        `xml:"-"`
+
+	//include所在的namespace，用于查找不包含namespace的refid
+	namespace string
+	//查找sql片段的方法
+	finder fragmentFinder
+	//当前include所在的片段链，用于检查循环引用
+	chain []string
+
+	lock     sync.Mutex
+	resolved bool
+	source   *Sql
+	contents []parsing.DynamicElement
 }
 
 type If struct {
-	Foreach Foreach `xml:"foreach"`
-	Test    string  `xml:"test,attr"`
-	Data    string  `xml:",chardata"`
+	Test string `xml:"test,attr"`
+	//按顺序保存的文本及动态元素
+	Contents []parsing.DynamicElement `xml:"-"`
+	//编译后的test表达式
+	expr *expr.Expr
+}
+
+// Trim 去除内容首尾匹配的字符串并添加前后缀，多个匹配字符串使用"|"分隔，匹配时不区分大小写
+type Trim struct {
+	Prefix          string `xml:"prefix,attr"`
+	Suffix          string `xml:"suffix,attr"`
+	PrefixOverrides string `xml:"prefixOverrides,attr"`
+	SuffixOverrides string `xml:"suffixOverrides,attr"`
+	//按顺序保存的文本及动态元素
+	Contents []parsing.DynamicElement `xml:"-"`
 }
 
+// Where 基于trim实现，内容不为空时添加where前缀并去除开头的AND或OR
 type Where struct {
-	Choose Choose `xml:"choose"`
-	If     []If   `xml:"if"`
+	Contents []parsing.DynamicElement `xml:"-"`
 }
 
+// Set 基于trim实现，内容不为空时添加set前缀，每个条件之间使用逗号分隔
 type Set struct {
-	If []If `xml:"if"`
+	Contents []parsing.DynamicElement `xml:"-"`
 }
 
+// Text 动态元素中的文本
+type Text string
+
 type When struct {
 	If
 }
 
 type Otherwise struct {
-	Data string `xml:",chardata"`
+	Contents []parsing.DynamicElement `xml:"-"`
 }
 
 type Choose struct {
@@ -89,44 +124,37 @@ func (de *Sql) Format(getFunc func(key string) string) string {
 
 //传入方法必须是通过参数名获得参数值
 func (de *If) Format(getFunc func(key string) string) string {
-	andStrs := strings.Split(de.Test, " and ")
-	orStrs := strings.Split(de.Test, " or ")
+	return de.FormatValue(parsing.NewValueContext(parsing.ValueGetter(getFunc)))
+}
 
-	if len(andStrs) > 1 && len(orStrs) > 1 {
-		logging.Warn(`error format. <if> element cannot both include " and " and " or "`)
+// FormatValue 使用参数的原始值计算test表达式，结果为true时返回内容
+func (de *If) FormatValue(ctx *parsing.DynamicContext) string {
+	if !de.Eval(ctx) {
 		return ""
 	}
+	return strings.Join(formatContents(de.Contents, ctx), " ")
+}
 
-	data := ""
-	if de.Foreach.Data != "" {
-		data = strings.TrimSpace(de.Foreach.Format(getFunc))
-	}
-	data += strings.TrimSpace(de.Data)
-	if len(andStrs) != 0 && len(orStrs) < 2 {
-		for _, v := range andStrs {
-			ret := Compare(v, getFunc)
-			if ret != true {
-				return ""
-			}
+// Eval 计算test表达式，表达式错误时打印警告并返回false
+func (de *If) Eval(ctx *parsing.DynamicContext) bool {
+	e := de.expr
+	if e == nil {
+		var err error
+		e, err = expr.Compile(de.Test)
+		if err != nil {
+			logging.Warn("%v\n", err)
+			return false
 		}
-		return data
 	}
-
-	ret := false
-	if len(orStrs) != 0 {
-		for _, v := range orStrs {
-			ret = Compare(v, getFunc)
-			if ret == true {
-				return data
-			}
-		}
-		if ret == false {
-			return ""
-		}
+	ret, err := e.EvalBool(ctx.Value)
+	if err != nil {
+		logging.Warn("eval test [%s] failed: %v\n", de.Test, err)
+		return false
 	}
-	return ""
+	return ret
 }
 
+// Compare 旧版本的test比较方法，仅支持==和!=的字符串比较，已被表达式替代
 //test的参数必须是使用{}包裹起来，并且比较符号需要空格分隔，如<if test="{1} != nil"> 或者 <if test="{x.name} != nil">
 func Compare(src string, getFunc func(key string) string) bool {
 	params := strings.Split(src, " ")
@@ -185,297 +213,131 @@ func getValueFromFunc(src string, getFunc func(key string) string) string {
 	return src
 }
 
-//传入方法必须是通过参数名获得参数值
-func (de *Include) Format(getFunc func(key string) string) string {
-	//TODO: sql的参数替换特性未实现
-	return de.Sql.Sql
+func (de Text) Format(getFunc func(key string) string) string {
+	return string(de)
 }
 
 //传入方法必须是通过参数名获得参数值
-func (de *Set) Format(getFunc func(key string) string) string {
-	ret := strings.Builder{}
-	if len(de.If) > 0 {
-		//ret.WriteString(" set ")
-		add := false
-		for i := range de.If {
-			ifStr := de.If[i].Format(getFunc)
-			if ifStr != "" {
-				if add {
-					ret.WriteString(",")
-				}
-
-				if ifStr[len(ifStr)-1:] == "," {
-					ret.WriteString(ifStr[:len(ifStr)-1])
-				} else {
-					ret.WriteString(ifStr)
-				}
-				add = true
-			}
-		}
-	}
-	retStr := ret.String()
-	if retStr != "" {
-		retStr = " set " + retStr
-	}
-	return retStr
+func (de *Trim) Format(getFunc func(key string) string) string {
+	return de.FormatValue(parsing.NewValueContext(parsing.ValueGetter(getFunc)))
 }
 
-//传入方法必须是通过参数名获得参数值
-func (de *Where) Format(getFunc func(key string) string) string {
-	ret := strings.Builder{}
-	if len(de.If) > 0 {
-		set := false
-		//ret.WriteString(" where ")
-		for i := range de.If {
-			ifStr := de.If[i].Format(getFunc)
-			if ifStr != "" {
-				if !set {
-					ifStr = removeAndOr(ifStr)
-					set = true
-				}
-				ret.WriteString(ifStr)
-				ret.WriteString(" ")
-			}
-		}
-	} else {
-		chooseStr := de.Choose.Format(getFunc)
-		chooseStr = removeAndOr(chooseStr)
-		if chooseStr != "" {
-			ret.WriteString(chooseStr)
-		}
-	}
-	retStr := ret.String()
-	if retStr != "" {
-		retStr = " where " + retStr
-	}
-	return retStr
+func (de *Trim) FormatValue(ctx *parsing.DynamicContext) string {
+	return de.apply(strings.Join(formatContents(de.Contents, ctx), " "))
 }
 
-func removeAndOr(src string) string {
-	if len(src) > 3 && strings.ToLower(src[:3]) == "or " {
-		src = strings.TrimSpace(src[3:])
-	} else if len(src) > 4 && strings.ToLower(src[:4]) == "and " {
-		src = strings.TrimSpace(src[4:])
+// apply 对内容去除匹配的首尾字符串并添加前后缀
+func (de *Trim) apply(content string) string {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return ""
 	}
-	return src
-}
+	content = strings.TrimSpace(trimOverrides(content, de.PrefixOverrides, true))
+	content = strings.TrimSpace(trimOverrides(content, de.SuffixOverrides, false))
 
-func (de *Choose) Format(getFunc func(key string) string) string {
 	ret := strings.Builder{}
-	if len(de.When) > 0 {
-		for i := range de.When {
-			ifStr := de.When[i].Format(getFunc)
-			if ifStr != "" {
-				ret.WriteString(ifStr)
-				ret.WriteString(" ")
-				break
-			}
-		}
+	if de.Prefix != "" {
+		ret.WriteString(" ")
+		ret.WriteString(de.Prefix)
 	}
-	retStr := ret.String()
-	if retStr == "" {
-		retStr = strings.TrimSpace(de.Otherwise.Data)
+	if content != "" {
+		ret.WriteString(" ")
+		ret.WriteString(content)
 	}
-	return retStr
-}
-
-func (de *Foreach) Format(getFunc func(key string) string) string {
-	if de.Collection == "" {
-		return ""
-	}
-
-	collectionKey := getKey(de.Collection)
-	value := getValueFromFunc(de.Collection, getFunc)
-	//if value == "" || value == "nil" {
-	//	return ""
-	//}
-	//
-	//elems := reflection.ParseSliceParamString(value)
-	//length := len(elems)
-	length, _ := strconv.Atoi(value)
-	ret := strings.Builder{}
-	ret.WriteString(de.Open)
-	indexStr := fmt.Sprintf("#{%s}", de.Index)
-
-	originData := strings.TrimSpace(de.Data)
-	realStr := ""
-	i := 0
-	for i < length {
-		realStr = strings.Replace(originData, de.Item, fmt.Sprintf("%s[%d]", collectionKey, i), -1)
-		realStr = strings.Replace(realStr, indexStr, strconv.Itoa(i), -1)
-		ret.WriteString(realStr)
-		if i < length-1 {
-			ret.WriteString(de.Separator)
-		}
-		i++
+	if de.Suffix != "" {
+		ret.WriteString(" ")
+		ret.WriteString(de.Suffix)
 	}
-	ret.WriteString(de.Close)
-
 	return ret.String()
 }
 
-func escape(src string) string {
-	src = strings.Replace(src, "&lt;", "<", -1)
-	src = strings.Replace(src, "&gt;", ">", -1)
-	src = strings.Replace(src, "&amp;", "&", -1)
-	src = strings.Replace(src, "&quot;", `"`, -1)
-	src = strings.Replace(src, "&apos;", `'`, -1)
-	return src
-}
-
-type typeProcessor interface {
-	EndStr() string
-	Parse(src string) parsing.DynamicElement
-}
-
-type IfProcessor string
-type WhereProcessor string
-type SetProcessor string
-type IncludeProcessor string
-type ChooseProcessor string
-type ForeachProcessor string
-
-var gProcessorMap = map[string]typeProcessor{
-	"if":      IfProcessor("if"),
-	"where":   WhereProcessor("where"),
-	"set":     SetProcessor("set"),
-	"include": IncludeProcessor("include"),
-	"choose":  ChooseProcessor("choose"),
-	"foreach": ForeachProcessor("foreach"),
-}
-
-func (d IfProcessor) EndStr() string {
-	return "</" + string(d) + ">"
-}
-
-func (d IfProcessor) Parse(src string) parsing.DynamicElement {
-	v := If{}
-	if xml.Unmarshal([]byte(src), &v) != nil {
-		logging.Warn("parse if element failed")
+// trimOverrides 去除第一个匹配的前缀或后缀，以空白结尾的匹配字符串也可以匹配换行等其他空白字符
+func trimOverrides(content, overrides string, prefix bool) string {
+	if overrides == "" {
+		return content
 	}
-	return &v
-}
-
-func (d WhereProcessor) EndStr() string {
-	return "</" + string(d) + ">"
-}
-
-func (d WhereProcessor) Parse(src string) parsing.DynamicElement {
-	v := Where{}
-	if xml.Unmarshal([]byte(src), &v) != nil {
-		logging.Warn("parse if element failed")
+	upper := strings.ToUpper(content)
+	for _, o := range strings.Split(overrides, "|") {
+		if o == "" {
+			continue
+		}
+		o = strings.ToUpper(o)
+		if prefix {
+			if strings.HasPrefix(upper, o) {
+				return content[len(o):]
+			}
+			t := strings.TrimRightFunc(o, unicode.IsSpace)
+			if t != o && t != "" && strings.HasPrefix(upper, t) && len(upper) > len(t) && unicode.IsSpace(rune(upper[len(t)])) {
+				return content[len(t):]
+			}
+		} else {
+			if strings.HasSuffix(upper, o) {
+				return content[:len(content)-len(o)]
+			}
+			t := strings.TrimLeftFunc(o, unicode.IsSpace)
+			if t != o && t != "" && strings.HasSuffix(upper, t) && len(upper) > len(t) && unicode.IsSpace(rune(upper[len(upper)-len(t)-1])) {
+				return content[:len(content)-len(t)]
+			}
+		}
 	}
-	return &v
+	return content
 }
 
-func (d SetProcessor) EndStr() string {
-	return "</" + string(d) + ">"
-}
+var whereTrim = Trim{Prefix: "where", PrefixOverrides: "AND |OR "}
+var setTrim = Trim{Prefix: "set", SuffixOverrides: ","}
 
-func (d SetProcessor) Parse(src string) parsing.DynamicElement {
-	v := Set{}
-	if xml.Unmarshal([]byte(src), &v) != nil {
-		logging.Warn("parse if element failed")
-	}
-	return &v
-}
-
-func (d IncludeProcessor) EndStr() string {
-	return "</" + string(d) + ">"
+//传入方法必须是通过参数名获得参数值
+func (de *Where) Format(getFunc func(key string) string) string {
+	return de.FormatValue(parsing.NewValueContext(parsing.ValueGetter(getFunc)))
 }
 
-func (d IncludeProcessor) Parse(src string) parsing.DynamicElement {
-	v := Include{}
-	if xml.Unmarshal([]byte(src), &v) != nil {
-		logging.Warn("parse if element failed")
-	}
-	return &v
+func (de *Where) FormatValue(ctx *parsing.DynamicContext) string {
+	return whereTrim.apply(strings.Join(formatContents(de.Contents, ctx), " "))
 }
 
-func (d ChooseProcessor) EndStr() string {
-	return "</" + string(d) + ">"
+//传入方法必须是通过参数名获得参数值
+func (de *Set) Format(getFunc func(key string) string) string {
+	return de.FormatValue(parsing.NewValueContext(parsing.ValueGetter(getFunc)))
 }
 
-func (d ChooseProcessor) Parse(src string) parsing.DynamicElement {
-	v := Choose{}
-	if xml.Unmarshal([]byte(src), &v) != nil {
-		logging.Warn("parse if element failed")
+func (de *Set) FormatValue(ctx *parsing.DynamicContext) string {
+	parts := make([]string, 0, len(de.Contents))
+	for _, c := range de.Contents {
+		s := strings.TrimSpace(parsing.FormatElement(c, ctx))
+		if s == "" {
+			continue
+		}
+		//兼容<if>末尾没有逗号的写法，其他内容与<trim>相同，需自行添加逗号
+		if _, ok := c.(*If); ok && !strings.HasSuffix(s, ",") {
+			s += ","
+		}
+		parts = append(parts, s)
 	}
-	return &v
-}
-
-func (d ForeachProcessor) EndStr() string {
-	return "</" + string(d) + ">"
+	return setTrim.apply(strings.Join(parts, " "))
 }
 
-func (d ForeachProcessor) Parse(src string) parsing.DynamicElement {
-	v := Foreach{}
-	if xml.Unmarshal([]byte(src), &v) != nil {
-		logging.Warn("parse if element failed")
+// formatContents 依次格式化内容，返回非空的结果
+func formatContents(contents []parsing.DynamicElement, ctx *parsing.DynamicContext) []string {
+	ret := make([]string, 0, len(contents))
+	for _, c := range contents {
+		s := strings.TrimSpace(parsing.FormatElement(c, ctx))
+		if s != "" {
+			ret = append(ret, s)
+		}
 	}
-	return &v
+	return ret
 }
 
-func ParseDynamic(src string, sqls []Sql) (*parsing.DynamicData, error) {
-	src = escape(src)
-
-	start, end := -1, -1
-	ret := &parsing.DynamicData{OriginData: src, DynamicElemMap: map[string]parsing.DynamicElement{}}
-	strData := []rune(src)
-	for i := 0; i < len(strData); {
-		r := strData[i]
-		if r == '<' {
-			start = i
-		}
-
-		if r == '>' {
-			end = i
-		}
-
-		if start < end && start != -1 && end != -1 {
-			subStr := src[start+1 : end]
-			subStr = strings.TrimLeftFunc(subStr, unicode.IsSpace)
-			index := strings.Index(subStr, " ")
-			if index != -1 {
-				subStr = subStr[:index]
-			}
-			logging.Debug("Found element : %s\n", subStr)
-			if typeProcessor, ok := gProcessorMap[subStr]; ok {
-				subStr = src[start:]
-				endStr := typeProcessor.EndStr()
-				index = strings.Index(subStr, endStr)
-				if index == -1 {
-					start, end = -1, -1
-					i++
-					start, end = -1, -1
-					continue
-				} else {
-					saveStr := subStr[:index+len(endStr)]
-					//logging.Debug("save element : %s\n", saveStr)
-					de := typeProcessor.Parse(saveStr)
-					if include, ok := de.(*Include); ok {
-						findSql(include, sqls)
-					}
-					ret.DynamicElemMap[saveStr] = de
-					i = start + index + 1 + len(endStr)
-					start, end = -1, -1
-					continue
-				}
-			}
-			start, end = -1, -1
-		}
-		i++
-	}
-	return ret, nil
+func (de *Choose) Format(getFunc func(key string) string) string {
+	return de.FormatValue(parsing.NewValueContext(parsing.ValueGetter(getFunc)))
 }
 
-func findSql(include *Include, sqls []Sql) {
-	if sqls != nil {
-		for i := range sqls {
-			if include.Refid == sqls[i].Id {
-				include.Sql = sqls[i]
-				return
-			}
+func (de *Choose) FormatValue(ctx *parsing.DynamicContext) string {
+	for i := range de.When {
+		if de.When[i].Eval(ctx) {
+			return strings.Join(formatContents(de.When[i].Contents, ctx), " ")
 		}
 	}
+	return strings.Join(formatContents(de.Otherwise.Contents, ctx), " ")
 }
+
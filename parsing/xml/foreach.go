@@ -0,0 +1,219 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xml
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/acmestack/gobatis/logging"
+	"github.com/acmestack/gobatis/parsing"
+	"github.com/acmestack/gobatis/parsing/expr"
+)
+
+// 匹配#{name}及${name}参数
+var gVarRegexp = regexp.MustCompile(`([#$])\{\s*([^{}]*?)\s*\}`)
+
+func (de *Foreach) Format(getFunc func(key string) string) string {
+	return de.FormatValue(parsing.NewValueContext(parsing.ValueGetter(getFunc)))
+}
+
+// FormatValue 对集合的每个元素格式化内容
+// collection可以是参数中任意路径的slice、array或map，如{0}、{User.Roles}、{item.Children}
+// 遍历slice、array时index为序号，遍历map时index为key，item为元素的值
+// 内容中的#{item}、#{item.x}、#{index}等参数绑定为新的参数，嵌套的元素也可以使用item及index
+// 内容中的<bind>每次迭代分别计算，引用绑定变量的参数同样绑定为新的参数
+func (de *Foreach) FormatValue(ctx *parsing.DynamicContext) string {
+	if de.Collection == "" {
+		return ""
+	}
+	e := de.collection
+	if e == nil {
+		var err error
+		e, err = expr.Compile(de.Collection)
+		if err != nil {
+			logging.Warn("%v\n", err)
+			return ""
+		}
+	}
+	collection, err := e.Eval(ctx.Value)
+	if err != nil {
+		logging.Warn("eval collection [%s] failed: %v\n", de.Collection, err)
+		return ""
+	}
+
+	ret := strings.Builder{}
+	ret.WriteString(de.Open)
+	rv := reflect.ValueOf(collection)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if i > 0 {
+				ret.WriteString(de.Separator)
+			}
+			ret.WriteString(de.formatItem(ctx, i, rv.Index(i).Interface()))
+		}
+	case reflect.Map:
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for i, k := range keys {
+			if i > 0 {
+				ret.WriteString(de.Separator)
+			}
+			ret.WriteString(de.formatItem(ctx, k.Interface(), rv.MapIndex(k).Interface()))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		//slice参数解析后保存为长度，元素使用{collection[i]}访问
+		ret.WriteString(de.formatIndexed(ctx, int(rv.Int())))
+	}
+	ret.WriteString(de.Close)
+
+	return ret.String()
+}
+
+// formatItem 在item及index的作用域中格式化内容，并将内容中引用item及index的参数绑定为唯一的参数名
+func (de *Foreach) formatItem(ctx *parsing.DynamicContext, index, item interface{}) string {
+	scope := ctx.Scope(func(key string) (interface{}, bool) {
+		if de.Item != "" && key == de.Item {
+			return item, true
+		}
+		if de.Index != "" && key == de.Index {
+			return index, true
+		}
+		return nil, false
+	})
+	content := strings.Join(formatContents(de.Contents, scope), " ")
+
+	seq := strconv.Itoa(ctx.NextSeq())
+	content = gVarRegexp.ReplaceAllStringFunc(content, func(s string) string {
+		m := gVarRegexp.FindStringSubmatch(s)
+		name := m[2]
+		var key, rest string
+		switch {
+		case de.Item != "" && (name == de.Item || strings.HasPrefix(name, de.Item+".") || strings.HasPrefix(name, de.Item+"[")):
+			key, rest = "_foreach_"+de.Item+"_"+seq, name[len(de.Item):]
+		case de.Index != "" && name == de.Index:
+			key = "_foreach_" + de.Index + "_" + seq
+		default:
+			return s
+		}
+		value, err := evalPath(name, scope)
+		if err != nil {
+			logging.Warn("eval foreach var [%s] failed: %v\n", name, err)
+			return s
+		}
+		ctx.Bind(key+rest, value)
+		return m[1] + "{" + key + rest + "}"
+	})
+	return bindScopeVars(ctx, scope, content, seq)
+}
+
+// formatIndexed 兼容slice参数解析后的格式，item替换为{collection[i]}，index替换为序号
+func (de *Foreach) formatIndexed(ctx *parsing.DynamicContext, length int) string {
+	collectionKey := getKey(de.Collection)
+	ret := strings.Builder{}
+	for i := 0; i < length; i++ {
+		itemKey := fmt.Sprintf("%s[%d]", collectionKey, i)
+		index := i
+		scope := ctx.Scope(func(key string) (interface{}, bool) {
+			if de.Index != "" && key == de.Index {
+				return index, true
+			}
+			if k := replaceItemKey(key, de.Item, itemKey); k != key {
+				return ctx.Value(k)
+			}
+			return nil, false
+		})
+		content := de.replaceVars(strings.Join(formatContents(de.Contents, scope), " "), itemKey, i)
+		ret.WriteString(bindScopeVars(ctx, scope, content, strconv.Itoa(ctx.NextSeq())))
+		if i < length-1 {
+			ret.WriteString(de.Separator)
+		}
+	}
+	return ret.String()
+}
+
+// replaceVars 将#{item}、#{item.x}等参数替换为集合元素的参数名，#{index}替换为序号
+func (de *Foreach) replaceVars(src, itemKey string, index int) string {
+	var pairs []string
+	if de.Item != "" {
+		pairs = append(pairs, "{"+de.Item+"}", "{"+itemKey+"}", "{"+de.Item+".", "{"+itemKey+".", "{"+de.Item+"[", "{"+itemKey+"[")
+	}
+	if de.Index != "" {
+		pairs = append(pairs, "#{"+de.Index+"}", strconv.Itoa(index))
+	}
+	if len(pairs) == 0 {
+		return src
+	}
+	return strings.NewReplacer(pairs...).Replace(src)
+}
+
+// bindScopeVars 将内容中引用作用域内<bind>变量的参数绑定为唯一的参数名，使每次迭代使用各自的值
+func bindScopeVars(ctx, scope *parsing.DynamicContext, content, seq string) string {
+	return gVarRegexp.ReplaceAllStringFunc(content, func(s string) string {
+		m := gVarRegexp.FindStringSubmatch(s)
+		name := m[2]
+		v := name
+		if i := strings.IndexAny(name, ".["); i >= 0 {
+			v = name[:i]
+		}
+		if !scope.HasVar(v) {
+			return s
+		}
+		value, err := evalPath(name, scope)
+		if err != nil {
+			logging.Warn("eval bind var [%s] failed: %v\n", name, err)
+			return s
+		}
+		key := "_bind_" + v + "_" + seq + name[len(v):]
+		ctx.Bind(key, value)
+		return m[1] + "{" + key + "}"
+	})
+}
+
+// replaceItemKey item参数名替换为集合元素的参数名
+func replaceItemKey(key, item, itemKey string) string {
+	if item == "" {
+		return key
+	}
+	if key == item {
+		return itemKey
+	}
+	if strings.HasPrefix(key, item+".") || strings.HasPrefix(key, item+"[") {
+		return itemKey + key[len(item):]
+	}
+	return key
+}
+
+// evalPath 获得item.x、item[0]等路径的值
+func evalPath(path string, ctx *parsing.DynamicContext) (interface{}, error) {
+	e, err := expr.Compile(path)
+	if err != nil {
+		return nil, err
+	}
+	return e.Eval(ctx.Value)
+}
@@ -0,0 +1,123 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xml
+
+import (
+	"encoding/xml"
+	"regexp"
+	"strings"
+
+	"github.com/acmestack/gobatis/logging"
+	"github.com/acmestack/gobatis/parsing"
+)
+
+// fragmentFinder 通过refid查找<sql>片段，refid不包含namespace时优先在namespace中查找
+type fragmentFinder func(namespace, refid string) (*Sql, bool)
+
+// 匹配${name}属性
+var gPropertyRegexp = regexp.MustCompile(`\$\{\s*([^{}]*?)\s*\}`)
+
+//传入方法必须是通过参数名获得参数值
+func (de *Include) Format(getFunc func(key string) string) string {
+	return de.FormatValue(parsing.NewValueContext(parsing.ValueGetter(getFunc)))
+}
+
+// FormatValue 格式化引用的sql片段，片段中的动态元素使用当前参数，片段中的<bind>绑定到当前作用域
+func (de *Include) FormatValue(ctx *parsing.DynamicContext) string {
+	contents, ok := de.resolve()
+	if !ok {
+		return de.Sql.Sql
+	}
+	return strings.Join(formatContents(contents, ctx), " ")
+}
+
+// resolve 查找sql片段，使用property替换片段中的${name}后解析为动态元素，解析成功后缓存结果
+func (de *Include) resolve() ([]parsing.DynamicElement, bool) {
+	de.lock.Lock()
+	defer de.lock.Unlock()
+
+	if de.finder != nil {
+		sql, ok := de.finder(de.namespace, de.Refid)
+		if !ok {
+			logging.Warn("include sql not found, refid: %s namespace: %s\n", de.Refid, de.namespace)
+			return nil, false
+		}
+		//片段重新加载后重新解析
+		if de.resolved && de.source == sql {
+			return de.contents, true
+		}
+		de.source = sql
+		de.Sql = *sql
+	} else if de.resolved {
+		return de.contents, true
+	}
+	key := de.Sql.namespace + "." + de.Sql.Id
+	for _, v := range de.chain {
+		if v == key {
+			logging.Warn("include sql circular reference: %s -> %s\n", strings.Join(de.chain, " -> "), key)
+			return nil, false
+		}
+	}
+
+	src := de.Sql.Data
+	if src == "" {
+		src = escapeText(de.Sql.Sql)
+	}
+	src = de.replaceProperties(src)
+	data := &parsing.DynamicData{}
+	p := &dynamicParser{
+		data:      data,
+		finder:    de.finder,
+		namespace: de.Sql.namespace,
+		chain:     append(append([]string{}, de.chain...), key),
+	}
+	contents, err := p.parse(src)
+	if err != nil {
+		logging.Warn("parse include sql failed, refid: %s err: %v\n", de.Refid, err)
+		return nil, false
+	}
+	de.contents = contents
+	de.resolved = true
+	return contents, true
+}
+
+// replaceProperties 使用property的值替换${name}，未定义的属性保留，在执行时作为参数替换
+func (de *Include) replaceProperties(src string) string {
+	if len(de.Properties) == 0 {
+		return src
+	}
+	props := make(map[string]string, len(de.Properties))
+	for _, p := range de.Properties {
+		props[p.Name] = p.Value
+	}
+	return gPropertyRegexp.ReplaceAllStringFunc(src, func(s string) string {
+		name := gPropertyRegexp.FindStringSubmatch(s)[1]
+		if v, ok := props[name]; ok {
+			return escapeText(v)
+		}
+		return s
+	})
+}
+
+func escapeText(s string) string {
+	buf := strings.Builder{}
+	if err := xml.EscapeText(&buf, []byte(s)); err != nil {
+		return s
+	}
+	return buf.String()
+}
@@ -18,6 +18,9 @@
 package xml
 
 import (
+	"io/fs"
+	"io/ioutil"
+	"strings"
 	"sync"
 
 	"github.com/acmestack/gobatis/errors"
@@ -28,53 +31,137 @@ import (
 
 type Manager struct {
 	sqlMap map[string]*parsing.DynamicData
-	lock   sync.Mutex
+	// 所有mapper的sql片段，key为namespace.id
+	fragments map[string]*Sql
+	// 每个mapper文件注册的语句及片段，用于重新加载
+	sources map[string]*mapperSource
+	lock    sync.Mutex
+}
+
+// mapperSource mapper文件注册的语句及sql片段的key
+type mapperSource struct {
+	statements []string
+	fragments  []string
 }
 
 func NewManager() *Manager {
 	return &Manager{
-		sqlMap: map[string]*parsing.DynamicData{},
+		sqlMap:    map[string]*parsing.DynamicData{},
+		fragments: map[string]*Sql{},
+		sources:   map[string]*mapperSource{},
 	}
 }
 
+// RegisterData 注册mapper数据，mapper中存在错误时返回包含所有错误的*ValidateError，不注册任何语句
 func (manager *Manager) RegisterData(data []byte) error {
-	manager.lock.Lock()
-	defer manager.lock.Unlock()
+	return manager.register("", data, false)
+}
 
-	mapper, err := Parse(data)
+// RegisterFile 注册mapper文件，mapper中存在错误时返回包含所有错误的*ValidateError，不注册任何语句
+func (manager *Manager) RegisterFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		logging.Warn("register mapper file failed: %s err: %v\n", file, err)
+		return err
+	}
+	return manager.register(file, data, false)
+}
+
+// RegisterFileFS 注册fsys中的mapper文件，可以用于embed.FS
+func (manager *Manager) RegisterFileFS(fsys fs.FS, file string) error {
+	data, err := fs.ReadFile(fsys, file)
 	if err != nil {
-		logging.Warn("register mapper data failed: %s err: %v\n", string(data), err)
+		logging.Warn("register mapper file failed: %s err: %v\n", file, err)
 		return err
 	}
+	return manager.register(file, data, false)
+}
 
-	return manager.formatMapper(mapper)
+// ReloadFile 重新加载mapper文件，使用新的语句及sql片段替换该文件之前注册的内容
+// 文件存在错误时返回错误并继续使用之前的版本
+func (manager *Manager) ReloadFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		logging.Warn("reload mapper file failed: %s err: %v\n", file, err)
+		return err
+	}
+	return manager.register(file, data, true)
 }
 
-func (manager *Manager) RegisterFile(file string) error {
+func (manager *Manager) register(file string, data []byte, reload bool) error {
 	manager.lock.Lock()
 	defer manager.lock.Unlock()
 
-	mapper, err := ParseFile(file)
+	mapper, err := Parse(data)
 	if err != nil {
-		logging.Warn("register mapper file failed: %s err: %v\n", file, err)
+		err = syntaxError(file, err)
+		logging.Warn("register mapper failed: %s err: %v\n", file, err)
+		return err
+	}
+	var old *mapperSource
+	if reload {
+		old = manager.sources[file]
+	}
+	if err := manager.validate(file, data, mapper, old); err != nil {
+		logging.Warn("register mapper failed: %v\n", err)
 		return err
 	}
 
-	return manager.formatMapper(mapper)
+	return manager.formatMapper(file, mapper, old)
 }
 
-func (manager *Manager) formatMapper(mapper *Mapper) error {
-	ret := mapper.Format()
+// formatMapper 注册mapper中的语句及sql片段，old不为空时先移除old中的内容
+func (manager *Manager) formatMapper(file string, mapper *Mapper, old *mapperSource) error {
+	c, err := mapper.CreateCache()
+	if err != nil {
+		logging.Warn("create mapper cache failed, namespace: %s err: %v\n", mapper.Namespace, err)
+		return err
+	}
+	ret := mapper.format(manager.findFragment)
+	if old != nil {
+		for _, k := range old.statements {
+			delete(manager.sqlMap, k)
+		}
+		for _, k := range old.fragments {
+			delete(manager.fragments, k)
+		}
+	}
+	src := &mapperSource{}
+	if file != "" {
+		manager.sources[file] = src
+	}
+	ns := strings.TrimSpace(mapper.Namespace)
+	for i := range mapper.Sql {
+		key := ns + "." + mapper.Sql[i].Id
+		manager.fragments[key] = &mapper.Sql[i]
+		src.fragments = append(src.fragments, key)
+	}
 	for k, v := range ret {
+		if c != nil && v.Statement != nil {
+			v.Statement.Cache = c
+		}
 		if _, ok := manager.sqlMap[k]; ok {
 			return errors.SqlIdDuplicates
 		} else {
 			manager.sqlMap[k] = v
+			src.statements = append(src.statements, k)
 		}
 	}
 	return nil
 }
 
+// findFragment 查找其他mapper的sql片段，refid不包含namespace时在namespace中查找
+func (manager *Manager) findFragment(namespace, refid string) (*Sql, bool) {
+	manager.lock.Lock()
+	defer manager.lock.Unlock()
+
+	if v, ok := manager.fragments[namespace+"."+refid]; ok {
+		return v, true
+	}
+	v, ok := manager.fragments[refid]
+	return v, ok
+}
+
 func (manager *Manager) FindSqlParser(sqlId string) (sqlparser.SqlParser, bool) {
 	manager.lock.Lock()
 	defer manager.lock.Unlock()
@@ -53,6 +53,11 @@ func Parse(data []byte) (*Mapper, error) {
 		logging.Warn("error: %v", err)
 		return nil, err
 	}
+	for i := range v.ResultMaps {
+		if ids := v.ResultMaps[i].ResultIds; len(ids) > 0 {
+			v.ResultMaps[i].ResultId = ids[0]
+		}
+	}
 	return &v, nil
 }
 
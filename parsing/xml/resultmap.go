@@ -17,7 +17,13 @@
 
 package xml
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/acmestack/gobatis/logging"
+	"github.com/acmestack/gobatis/reflection"
+)
 
 type IdArg struct {
 	Column string `xml:"column,attr"`
@@ -34,20 +40,204 @@ type Result struct {
 	Column   string `xml:"column,attr"`
 }
 
+// ResultMapping resultMap、association、collection及case中共同的映射元素
+type ResultMapping struct {
+	//一个 ID 结果；标记出作为 ID 的结果，用于对一对多查询的结果分组
+	ResultIds []Result `xml:"id"`
+	//注入到字段或 Struct 属性的普通结果
+	Results []Result `xml:"result"`
+	//association: 一个复杂类型的关联，对应结构体或结构体指针字段
+	Associations []Association `xml:"association"`
+	//collection: 一个复杂类型的集合，对应slice字段
+	Collections []Collection `xml:"collection"`
+	//discriminator: 使用结果值来决定使用哪个 resultMap
+	Discriminator *Discriminator `xml:"discriminator"`
+}
+
 type ResultMap struct {
 	XMLName xml.Name
 	//id
 	Id string `xml:"id,attr"`
 	//struct类型名称
 	TypeName string `xml:"type,attr"`
+	//继承的resultMap id
+	Extends string `xml:"extends,attr"`
 	//constructor - 用于在实例化类时，注入结果到构造方法中
 	Constructor Constructor `xml:"constructor"`
-	//一个 ID 结果；标记出作为 ID 的结果可以帮助提高整体性能
-	ResultId Result `xml:"id"`
-	//注入到字段或 Struct 属性的普通结果
-	Results []Result `xml:"result"`
-	//TODO:
-	//association: 一个复杂类型的关联；许多结果将包装成这种类型
-	//collection: 一个复杂类型的集合
-	//discriminator: 使用结果值来决定使用哪个 resultMap
+	// Deprecated: 使用ResultIds，解析时设置为第一个id元素；ResultIds为空时使用该字段
+	ResultId Result `xml:"-"`
+
+	ResultMapping
+}
+
+type Association struct {
+	Property string `xml:"property,attr"`
+	GoType   string `xml:"type,attr"`
+	//引用的resultMap id，为空时使用内部的映射元素
+	ResultMap    string `xml:"resultMap,attr"`
+	ColumnPrefix string `xml:"columnPrefix,attr"`
+
+	ResultMapping
+}
+
+type Collection struct {
+	Property string `xml:"property,attr"`
+	OfType   string `xml:"ofType,attr"`
+	//引用的resultMap id，为空时使用内部的映射元素
+	ResultMap    string `xml:"resultMap,attr"`
+	ColumnPrefix string `xml:"columnPrefix,attr"`
+
+	ResultMapping
+}
+
+type Discriminator struct {
+	Column string `xml:"column,attr"`
+	Cases  []Case `xml:"case"`
+}
+
+type Case struct {
+	Value string `xml:"value,attr"`
+	//引用的resultMap id，为空时使用内部的映射元素并继承外层resultMap的映射
+	ResultMap string `xml:"resultMap,attr"`
+
+	ResultMapping
+}
+
+// resultMapBuilder 将mapper中的resultMap元素解析为reflection.ResultMap
+type resultMapBuilder struct {
+	namespace string
+	defines   map[string]*ResultMap
+	built     map[string]*reflection.ResultMap
+}
+
+func newResultMapBuilder(mapper *Mapper) *resultMapBuilder {
+	ret := &resultMapBuilder{
+		namespace: strings.TrimSpace(mapper.Namespace),
+		defines:   map[string]*ResultMap{},
+		built:     map[string]*reflection.ResultMap{},
+	}
+	for i := range mapper.ResultMaps {
+		ret.defines[mapper.ResultMaps[i].Id] = &mapper.ResultMaps[i]
+	}
+	return ret
+}
+
+// find 获得resultMap，id可以包含当前namespace
+func (builder *resultMapBuilder) find(id string) *reflection.ResultMap {
+	id = strings.TrimSpace(id)
+	if builder.namespace != "" {
+		id = strings.TrimPrefix(id, builder.namespace+".")
+	}
+	if rm, ok := builder.built[id]; ok {
+		return rm
+	}
+	def, ok := builder.defines[id]
+	if !ok {
+		logging.Warn("ResultMap not found, namespace: %s id: %s\n", builder.namespace, id)
+		return nil
+	}
+	rm := &reflection.ResultMap{Id: id}
+	//先保存，允许嵌套映射引用自身
+	builder.built[id] = rm
+	if def.Extends != "" {
+		if parent := builder.find(def.Extends); parent != nil {
+			*rm = *parent
+			rm.Id = id
+		}
+	}
+	mapping := def.ResultMapping
+	if len(mapping.ResultIds) == 0 && def.ResultId != (Result{}) {
+		mapping.ResultIds = []Result{def.ResultId}
+	}
+	builder.fill(rm, &mapping)
+	return rm
+}
+
+// fill 将映射元素添加到rm中，已有的映射将被覆盖
+func (builder *resultMapBuilder) fill(rm *reflection.ResultMap, mapping *ResultMapping) {
+	rm.Ids = appendProperties(rm.Ids, mapping.ResultIds)
+	rm.Results = appendProperties(rm.Results, mapping.Results)
+	for _, a := range mapping.Associations {
+		if n := builder.nested(a.Property, a.ResultMap, a.ColumnPrefix, &a.ResultMapping); n != nil {
+			rm.Associations = appendNested(rm.Associations, n)
+		}
+	}
+	for _, c := range mapping.Collections {
+		if n := builder.nested(c.Property, c.ResultMap, c.ColumnPrefix, &c.ResultMapping); n != nil {
+			rm.Collections = appendNested(rm.Collections, n)
+		}
+	}
+	if mapping.Discriminator != nil {
+		d := &reflection.Discriminator{
+			Column: mapping.Discriminator.Column,
+			Cases:  map[string]*reflection.ResultMap{},
+		}
+		for _, c := range mapping.Discriminator.Cases {
+			var caseMap *reflection.ResultMap
+			if c.ResultMap != "" {
+				caseMap = builder.find(c.ResultMap)
+			} else {
+				//内联的case继承外层的映射
+				caseMap = &reflection.ResultMap{
+					Id:           rm.Id,
+					Ids:          rm.Ids,
+					Results:      rm.Results,
+					Associations: rm.Associations,
+					Collections:  rm.Collections,
+				}
+				builder.fill(caseMap, &c.ResultMapping)
+			}
+			if caseMap != nil {
+				d.Cases[c.Value] = caseMap
+			}
+		}
+		rm.Discriminator = d
+	}
+}
+
+func (builder *resultMapBuilder) nested(property, ref, prefix string, mapping *ResultMapping) *reflection.NestedResult {
+	var rm *reflection.ResultMap
+	if ref != "" {
+		rm = builder.find(ref)
+		if rm == nil {
+			return nil
+		}
+	} else {
+		rm = &reflection.ResultMap{}
+		builder.fill(rm, mapping)
+	}
+	return &reflection.NestedResult{
+		Property:     property,
+		ColumnPrefix: prefix,
+		ResultMap:    rm,
+	}
+}
+
+func appendProperties(props []reflection.ResultProperty, results []Result) []reflection.ResultProperty {
+	ret := append([]reflection.ResultProperty{}, props...)
+	for _, r := range results {
+		p := reflection.ResultProperty{Property: r.Property, Column: r.Column}
+		replaced := false
+		for i := range ret {
+			if ret[i].Property == p.Property {
+				ret[i] = p
+				replaced = true
+			}
+		}
+		if !replaced {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+func appendNested(nested []*reflection.NestedResult, n *reflection.NestedResult) []*reflection.NestedResult {
+	ret := append([]*reflection.NestedResult{}, nested...)
+	for i := range ret {
+		if ret[i].Property == n.Property {
+			ret[i] = n
+			return ret
+		}
+	}
+	return append(ret, n)
 }
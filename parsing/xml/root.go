@@ -18,14 +18,19 @@
 package xml
 
 import (
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/acmestack/gobatis/cache"
+	"github.com/acmestack/gobatis/errors"
 	"github.com/acmestack/gobatis/logging"
 	"github.com/acmestack/gobatis/parsing"
 )
 
 type Mapper struct {
 	Namespace  string      `xml:"namespace,attr"`
+	Cache      *Cache      `xml:"cache"`
 	ResultMaps []ResultMap `xml:"resultMap"`
 	Sql        []Sql       `xml:"sql"`
 
@@ -36,18 +41,45 @@ type Mapper struct {
 }
 
 func (mapper *Mapper) Format() map[string]*parsing.DynamicData {
+	return mapper.format(nil)
+}
+
+// format 解析mapper中的语句，include优先在当前mapper中查找sql片段，找不到时使用global查找
+func (mapper *Mapper) format(global fragmentFinder) map[string]*parsing.DynamicData {
 	ret := map[string]*parsing.DynamicData{}
-	keyPre := strings.TrimSpace(mapper.Namespace)
+	resultMaps := newResultMapBuilder(mapper)
+	ns := strings.TrimSpace(mapper.Namespace)
+	keyPre := ns
 	if keyPre != "" {
 		keyPre = keyPre + "."
 	}
+	for i := range mapper.Sql {
+		mapper.Sql[i].namespace = ns
+	}
+	finder := func(namespace, refid string) (*Sql, bool) {
+		if namespace == ns {
+			if sql, ok := findSql(mapper.Sql, ns, refid); ok {
+				return sql, true
+			}
+		}
+		if global != nil {
+			return global(namespace, refid)
+		}
+		return nil, false
+	}
 	for _, v := range mapper.Insert {
 		key := keyPre + v.Id
 		if d, ok := ret[key]; ok {
 			logging.Warn("Insert Sql id is duplicates, id: %s, before: %s, after %s\n", v.Id, d, v.Data)
 		}
-		d, err := ParseDynamic(strings.TrimSpace(v.Data), mapper.Sql)
+		d, err := parseDynamic(strings.TrimSpace(v.Data), ns, finder)
 		if err == nil {
+			d.Statement = parsing.NewStatement(ns, key, "insert", "", v.FlushCache)
+			d.Statement.Timeout = parseTimeout(key, v.Timeout)
+			d.Statement.UseGeneratedKeys = parsing.ParseBool(v.UseGeneratedKeys, false)
+			d.Statement.KeyProperty = parsing.ParseNames(v.KeyProperty)
+			d.Statement.KeyColumn = parsing.ParseNames(v.KeyColumn)
+			d.Statement.SelectKey = formatSelectKey(v.SelectKey, ns, finder)
 			ret[key] = d
 		}
 	}
@@ -56,8 +88,11 @@ func (mapper *Mapper) Format() map[string]*parsing.DynamicData {
 		if d, ok := ret[key]; ok {
 			logging.Warn("Update Sql id is duplicates, id: %s, before: %s, after %s\n", v.Id, d, v.Data)
 		}
-		d, err := ParseDynamic(strings.TrimSpace(v.Data), mapper.Sql)
+		d, err := parseDynamic(strings.TrimSpace(v.Data), ns, finder)
 		if err == nil {
+			d.Statement = parsing.NewStatement(ns, key, "update", "", v.FlushCache)
+			d.Statement.Timeout = parseTimeout(key, v.Timeout)
+			d.Statement.SelectKey = formatSelectKey(v.SelectKey, ns, finder)
 			ret[key] = d
 		}
 	}
@@ -66,20 +101,104 @@ func (mapper *Mapper) Format() map[string]*parsing.DynamicData {
 		if d, ok := ret[key]; ok {
 			logging.Warn("Select Sql id is duplicates, id: %s, before: %s, after %s\n", v.Id, d, v.Data)
 		}
-		d, err := ParseDynamic(strings.TrimSpace(v.Data), mapper.Sql)
+		d, err := parseDynamic(strings.TrimSpace(v.Data), ns, finder)
 		if err == nil {
+			d.Statement = parsing.NewStatement(ns, key, "select", v.UseCache, v.FlushCache)
+			d.Statement.Timeout = parseTimeout(key, v.Timeout)
+			d.Statement.FetchSize = parseFetchSize(key, v.FetchSize)
+			if v.ResultMap != "" {
+				d.Statement.ResultMap = resultMaps.find(v.ResultMap)
+			}
 			ret[key] = d
 		}
 	}
 	for _, v := range mapper.Delete {
 		key := keyPre + v.Id
-		if d, ok := ret[v.Id]; ok {
+		if d, ok := ret[key]; ok {
 			logging.Warn("Delete Sql id is duplicates, id: %s, before: %s, after %s\n", v.Id, d, v.Data)
 		}
-		d, err := ParseDynamic(strings.TrimSpace(v.Data), mapper.Sql)
+		d, err := parseDynamic(strings.TrimSpace(v.Data), ns, finder)
 		if err == nil {
+			d.Statement = parsing.NewStatement(ns, key, "delete", "", v.FlushCache)
+			d.Statement.Timeout = parseTimeout(key, v.Timeout)
 			ret[key] = d
 		}
 	}
 	return ret
 }
+
+// formatSelectKey 解析<selectKey>，order默认为AFTER
+func formatSelectKey(v *SelectKey, namespace string, finder fragmentFinder) *parsing.SelectKey {
+	if v == nil {
+		return nil
+	}
+	d, err := parseDynamic(strings.TrimSpace(v.Data), namespace, finder)
+	if err != nil {
+		return nil
+	}
+	order := strings.ToUpper(strings.TrimSpace(v.Order))
+	if order != parsing.SelectKeyBefore {
+		order = parsing.SelectKeyAfter
+	}
+	return &parsing.SelectKey{
+		Order:       order,
+		KeyProperty: parsing.ParseNames(v.KeyProperty),
+		KeyColumn:   parsing.ParseNames(v.KeyColumn),
+		Sql:         d,
+	}
+}
+
+// Cache mapper的二级缓存配置
+type Cache struct {
+	Type     string `xml:"type,attr"`
+	Eviction string `xml:"eviction,attr"`
+	Size     string `xml:"size,attr"`
+	// FlushInterval 自动清空间隔，单位毫秒
+	FlushInterval string `xml:"flushInterval,attr"`
+	ReadOnly      string `xml:"readOnly,attr"`
+}
+
+// parseTimeout 解析语句的timeout属性，无法解析时打印警告并忽略
+func parseTimeout(id, s string) time.Duration {
+	d, ok := parsing.ParseTimeout(s)
+	if !ok {
+		logging.Warn("Sql timeout is invalid and ignored, id: %s, timeout: %s\n", id, s)
+	}
+	return d
+}
+
+// parseFetchSize 解析select的fetchSize属性，无法解析时打印警告并忽略
+func parseFetchSize(id, s string) int {
+	i, ok := parsing.ParseFetchSize(s)
+	if !ok {
+		logging.Warn("Sql fetchSize is invalid and ignored, id: %s, fetchSize: %s\n", id, s)
+	}
+	return i
+}
+
+// CreateCache 根据<cache>元素创建namespace的二级缓存，未配置时返回nil
+func (mapper *Mapper) CreateCache() (cache.Cache, error) {
+	if mapper.Cache == nil {
+		return nil, nil
+	}
+	config := cache.Config{
+		Namespace: strings.TrimSpace(mapper.Namespace),
+		Type:      strings.TrimSpace(mapper.Cache.Type),
+		Eviction:  strings.ToUpper(strings.TrimSpace(mapper.Cache.Eviction)),
+	}
+	if mapper.Cache.Size != "" {
+		size, err := strconv.Atoi(mapper.Cache.Size)
+		if err != nil {
+			return nil, errors.CacheConfigError
+		}
+		config.Size = size
+	}
+	if mapper.Cache.FlushInterval != "" {
+		interval, err := strconv.ParseInt(mapper.Cache.FlushInterval, 10, 64)
+		if err != nil {
+			return nil, errors.CacheConfigError
+		}
+		config.FlushInterval = time.Duration(interval) * time.Millisecond
+	}
+	return cache.NewCache(config)
+}
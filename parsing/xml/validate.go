@@ -0,0 +1,439 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/parsing/expr"
+)
+
+// Problem mapper文件中的一个错误
+type Problem struct {
+	File string
+	Line int
+	// Id 错误所在的语句或sql片段id
+	Id      string
+	Message string
+}
+
+func (p Problem) String() string {
+	ret := strings.Builder{}
+	if p.File != "" {
+		ret.WriteString(p.File)
+		ret.WriteString(":")
+	}
+	ret.WriteString(fmt.Sprintf("%d: ", p.Line))
+	if p.Id != "" {
+		ret.WriteString("[")
+		ret.WriteString(p.Id)
+		ret.WriteString("] ")
+	}
+	ret.WriteString(p.Message)
+	return ret.String()
+}
+
+// ValidateError 包含mapper文件中的所有错误
+type ValidateError struct {
+	Problems []Problem
+}
+
+func (e *ValidateError) Error() string {
+	ret := strings.Builder{}
+	ret.WriteString(errors.MapperValidateError.Error())
+	for _, p := range e.Problems {
+		ret.WriteString("\n\t")
+		ret.WriteString(p.String())
+	}
+	return ret.String()
+}
+
+// Unwrap 可以使用errors.Is(err, errors.MapperValidateError)判断
+func (e *ValidateError) Unwrap() error {
+	return errors.MapperValidateError
+}
+
+// 匹配#{name}及${name}参数
+var gParamRegexp = regexp.MustCompile(`[#$]\{([^{}]*)\}`)
+
+// 合法的参数名，如id、TestTable.id、0[1].id
+var gParamNameRegexp = regexp.MustCompile(`^[\p{L}\p{N}_]+(\.[\p{L}\p{N}_]+|\[\d+\])*$`)
+
+// validator 检查mapper文件，记录所有错误的位置
+type validator struct {
+	file      string
+	data      []byte
+	namespace string
+	mapper    *Mapper
+	//查找已注册的语句、sql片段及namespace
+	registered func(key string) bool
+	fragment   fragmentFinder
+	hasNs      func(namespace string) bool
+
+	decoder  *xml.Decoder
+	offset   int64
+	line     int
+	ids      map[string]int
+	problems []Problem
+}
+
+// validate 检查mapper中的元素、include引用、resultMap引用、重复id及参数名，没有错误时返回nil
+// old为重新加载的文件之前注册的内容，不作为重复的语句
+func (manager *Manager) validate(file string, data []byte, mapper *Mapper, old *mapperSource) error {
+	replaced := map[string]bool{}
+	if old != nil {
+		for _, k := range old.statements {
+			replaced[k] = true
+		}
+	}
+	v := &validator{
+		file:      file,
+		data:      data,
+		namespace: strings.TrimSpace(mapper.Namespace),
+		mapper:    mapper,
+		registered: func(key string) bool {
+			_, ok := manager.sqlMap[key]
+			return ok && !replaced[key]
+		},
+		fragment: func(namespace, refid string) (*Sql, bool) {
+			if v, ok := manager.fragments[namespace+"."+refid]; ok {
+				return v, true
+			}
+			v, ok := manager.fragments[refid]
+			return v, ok
+		},
+		hasNs: manager.hasNamespace,
+		line:  1,
+		ids:   map[string]int{},
+	}
+	v.run()
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return &ValidateError{Problems: v.problems}
+}
+
+// hasNamespace namespace是否已经注册
+func (manager *Manager) hasNamespace(namespace string) bool {
+	prefix := namespace + "."
+	for k := range manager.sqlMap {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	for k := range manager.fragments {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// syntaxError 将xml解析错误转换为ValidateError
+func syntaxError(file string, err error) error {
+	if e, ok := err.(*xml.SyntaxError); ok {
+		return &ValidateError{Problems: []Problem{{File: file, Line: e.Line, Message: e.Msg}}}
+	}
+	return err
+}
+
+func (v *validator) addf(line int, id, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{
+		File:    v.file,
+		Line:    line,
+		Id:      id,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// lineAt 获得偏移位置所在的行，偏移只会递增
+func (v *validator) lineAt(offset int64) int {
+	if offset > int64(len(v.data)) {
+		offset = int64(len(v.data))
+	}
+	if offset > v.offset {
+		v.line += bytes.Count(v.data[v.offset:offset], []byte("\n"))
+		v.offset = offset
+	}
+	return v.line
+}
+
+// next 读取下一个token，返回token开始的行
+func (v *validator) next() (xml.Token, int, error) {
+	line := v.lineAt(v.decoder.InputOffset())
+	t, err := v.decoder.Token()
+	return t, line, err
+}
+
+func (v *validator) run() {
+	v.decoder = xml.NewDecoder(bytes.NewReader(v.data))
+	for {
+		t, line, err := v.next()
+		if err != nil {
+			return
+		}
+		if start, ok := t.(xml.StartElement); ok {
+			if start.Name.Local != MapperStart {
+				v.addf(line, "", "root element must be <%s>, get <%s>", MapperStart, start.Name.Local)
+				return
+			}
+			v.mapperElements()
+			return
+		}
+	}
+}
+
+func (v *validator) mapperElements() {
+	for {
+		t, line, err := v.next()
+		if err != nil {
+			return
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "cache":
+				v.skip()
+			case "resultMap":
+				v.checkId(line, "resultMap", attr(t, "id"))
+				v.checkResultMapRef(line, attr(t, "id"), attr(t, "extends"))
+				v.resultMapElements(attr(t, "id"))
+			case "sql":
+				v.checkId(line, "sql", attr(t, "id"))
+				v.contents(attr(t, "id"), "sql")
+			case "select":
+				id := v.checkStatementId(line, t)
+				v.checkResultMapRef(line, id, attr(t, "resultMap"))
+				v.contents(id, t.Name.Local)
+			case "insert", "update", "delete":
+				v.contents(v.checkStatementId(line, t), t.Name.Local)
+			default:
+				v.addf(line, "", "unknown element <%s> in mapper", t.Name.Local)
+				v.skip()
+			}
+		case xml.EndElement:
+			return
+		}
+	}
+}
+
+// checkId 检查id不为空且在mapper中不重复，resultMap、sql及语句分别检查
+func (v *validator) checkId(line int, kind, id string) {
+	if strings.TrimSpace(id) == "" {
+		v.addf(line, "", "<%s> id is empty", kind)
+		return
+	}
+	key := kind + ":" + id
+	if first, ok := v.ids[key]; ok {
+		v.addf(line, id, "duplicate %s id, first defined at line %d", kind, first)
+		return
+	}
+	v.ids[key] = line
+}
+
+func (v *validator) checkStatementId(line int, start xml.StartElement) string {
+	id := attr(start, "id")
+	v.checkId(line, "statement", id)
+	key := id
+	if v.namespace != "" {
+		key = v.namespace + "." + id
+	}
+	if id != "" && v.registered(key) {
+		v.addf(line, id, "statement %s already registered", key)
+	}
+	return id
+}
+
+func (v *validator) checkResultMapRef(line int, id, ref string) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return
+	}
+	if v.namespace != "" {
+		ref = strings.TrimPrefix(ref, v.namespace+".")
+	}
+	for i := range v.mapper.ResultMaps {
+		if v.mapper.ResultMaps[i].Id == ref {
+			return
+		}
+	}
+	v.addf(line, id, "resultMap %s not found", ref)
+}
+
+// resultMapElements 检查resultMap中association、collection及case引用的resultMap
+func (v *validator) resultMapElements(id string) {
+	for {
+		t, line, err := v.next()
+		if err != nil {
+			return
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "id", "result", "constructor", "idArg", "arg", "discriminator":
+			case "association", "collection", "case":
+				v.checkResultMapRef(line, id, attr(t, "resultMap"))
+			default:
+				v.addf(line, id, "unknown element <%s> in resultMap", t.Name.Local)
+			}
+			v.resultMapElements(id)
+		case xml.EndElement:
+			return
+		}
+	}
+}
+
+// contents 检查语句或sql片段中的动态元素及参数名，parent为所在元素的名称
+func (v *validator) contents(id, parent string) {
+	for {
+		t, line, err := v.next()
+		if err != nil {
+			return
+		}
+		switch t := t.(type) {
+		case xml.CharData:
+			v.checkParams(line, id, string(t))
+		case xml.StartElement:
+			v.element(line, id, parent, t)
+		case xml.EndElement:
+			return
+		}
+	}
+}
+
+func (v *validator) element(line int, id, parent string, start xml.StartElement) {
+	name := start.Name.Local
+	switch name {
+	case "if":
+		v.checkExpr(line, id, name, "test", attr(start, "test"))
+	case "when", "otherwise":
+		if parent != "choose" {
+			v.addf(line, id, "<%s> must be in <choose>", name)
+		}
+		if name == "when" {
+			v.checkExpr(line, id, name, "test", attr(start, "test"))
+		}
+	case "choose", "trim", "where", "set":
+	case "foreach":
+		v.checkExpr(line, id, name, "collection", attr(start, "collection"))
+	case "bind":
+		if attr(start, "name") == "" {
+			v.addf(line, id, "<bind> name is empty")
+		}
+		v.checkExpr(line, id, name, "value", attr(start, "value"))
+	case "include":
+		v.checkInclude(line, id, attr(start, "refid"))
+		v.includeElements(id)
+		return
+	case "selectKey":
+		if parent != "insert" && parent != "update" {
+			v.addf(line, id, "<selectKey> must be in <insert> or <update>")
+		}
+	default:
+		v.addf(line, id, "unknown element <%s>", name)
+		v.skip()
+		return
+	}
+	if parent == "choose" && name != "when" && name != "otherwise" {
+		v.addf(line, id, "<%s> not support in <choose>", name)
+	}
+	v.contents(id, name)
+}
+
+func (v *validator) checkExpr(line int, id, element, name, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf(line, id, "<%s> %s is empty", element, name)
+		return
+	}
+	if _, err := expr.Compile(value); err != nil {
+		v.addf(line, id, "<%s> %s [%s] invalid: %v", element, name, value, err)
+	}
+}
+
+// checkInclude 检查include引用的sql片段，引用的namespace尚未注册时在使用时查找，不做检查
+func (v *validator) checkInclude(line int, id, refid string) {
+	refid = strings.TrimSpace(refid)
+	if refid == "" {
+		v.addf(line, id, "<include> refid is empty")
+		return
+	}
+	//引用由property决定时无法检查
+	if strings.Contains(refid, "${") {
+		return
+	}
+	if _, ok := findSql(v.mapper.Sql, v.namespace, refid); ok {
+		return
+	}
+	if _, ok := v.fragment(v.namespace, refid); ok {
+		return
+	}
+	if i := strings.LastIndex(refid, "."); i != -1 {
+		ns := refid[:i]
+		if ns != v.namespace && !v.hasNs(ns) {
+			return
+		}
+	}
+	v.addf(line, id, "include refid %s not found", refid)
+}
+
+func (v *validator) includeElements(id string) {
+	for {
+		t, line, err := v.next()
+		if err != nil {
+			return
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "property" {
+				v.addf(line, id, "unknown element <%s> in <include>", t.Name.Local)
+			} else if attr(t, "name") == "" {
+				v.addf(line, id, "<property> name is empty")
+			}
+			v.skip()
+		case xml.EndElement:
+			return
+		}
+	}
+}
+
+// checkParams 检查文本中的#{}及${}参数名，参数名为空或包含非法字符时无法绑定参数
+func (v *validator) checkParams(line int, id, text string) {
+	for _, m := range gParamRegexp.FindAllStringSubmatchIndex(text, -1) {
+		name := text[m[2]:m[3]]
+		if !gParamNameRegexp.MatchString(name) {
+			l := line + strings.Count(text[:m[0]], "\n")
+			v.addf(l, id, "parameter %s can never be bound", text[m[0]:m[1]])
+		}
+	}
+	rest := gParamRegexp.ReplaceAllString(text, "")
+	for _, p := range []string{"#{", "${"} {
+		if i := strings.Index(rest, p); i != -1 {
+			v.addf(line+strings.Count(rest[:i], "\n"), id, "parameter %s is not closed", p)
+		}
+	}
+}
+
+// skip 跳过当前元素，xml格式错误在解析mapper时已经返回
+func (v *validator) skip() {
+	_ = v.decoder.Skip()
+}
@@ -0,0 +1,150 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugin
+
+import (
+	"context"
+	"sync"
+
+	"github.com/acmestack/gobatis/executor"
+	"github.com/acmestack/gobatis/parsing/sqlparser"
+)
+
+type sqlIdKey struct{}
+
+// Interceptor 拦截器，用于实现链路追踪、多租户过滤、审计及sql改写等功能
+type Interceptor interface {
+	// InterceptMetadata 在sql执行前调用，可以观察或修改sql语句及参数
+	// 返回的Metadata将用于执行，返回error时终止执行并作为Result的返回值
+	InterceptMetadata(ctx context.Context, sqlId string, md *sqlparser.Metadata) (*sqlparser.Metadata, error)
+
+	// WrapExecutor 在创建Session时包装执行器，不需要包装时直接返回参数
+	// 包装后的执行器需要实现executor.Wrapper，才能使用被包装执行器的FlushStatements等可选接口
+	WrapExecutor(e executor.Executor) executor.Executor
+
+	// InterceptResult 在sql执行及结果映射完成后调用，bean为Result的参数，err为执行的错误
+	// 返回的错误将作为Result的返回值
+	InterceptResult(ctx context.Context, sqlId string, md *sqlparser.Metadata, bean interface{}, err error) error
+}
+
+// BaseInterceptor 不做任何处理的拦截器，可以嵌入自定义的拦截器中，只实现需要的方法
+type BaseInterceptor struct{}
+
+func (i BaseInterceptor) InterceptMetadata(ctx context.Context, sqlId string, md *sqlparser.Metadata) (*sqlparser.Metadata, error) {
+	return md, nil
+}
+
+func (i BaseInterceptor) WrapExecutor(e executor.Executor) executor.Executor {
+	return e
+}
+
+func (i BaseInterceptor) InterceptResult(ctx context.Context, sqlId string, md *sqlparser.Metadata, bean interface{}, err error) error {
+	return err
+}
+
+// InterceptorChain 拦截器链
+// InterceptMetadata按注册顺序调用；WrapExecutor先注册的拦截器在最外层；InterceptResult按注册的逆序调用
+// Add可以与其他方法并发调用，添加时复制生成新的slice，已获得的拦截器列表不会被修改
+type InterceptorChain struct {
+	lock         sync.RWMutex
+	interceptors []Interceptor
+}
+
+func NewInterceptorChain(interceptors ...Interceptor) *InterceptorChain {
+	return &InterceptorChain{interceptors: interceptors}
+}
+
+// Add 添加拦截器，对已经创建的Session不生效
+func (chain *InterceptorChain) Add(interceptors ...Interceptor) {
+	chain.lock.Lock()
+	defer chain.lock.Unlock()
+
+	list := make([]Interceptor, 0, len(chain.interceptors)+len(interceptors))
+	list = append(list, chain.interceptors...)
+	chain.interceptors = append(list, interceptors...)
+}
+
+// Interceptors 获得所有拦截器
+func (chain *InterceptorChain) Interceptors() []Interceptor {
+	if chain == nil {
+		return nil
+	}
+	chain.lock.RLock()
+	defer chain.lock.RUnlock()
+
+	return chain.interceptors
+}
+
+func (chain *InterceptorChain) Len() int {
+	if chain == nil {
+		return 0
+	}
+	return len(chain.Interceptors())
+}
+
+func (chain *InterceptorChain) InterceptMetadata(ctx context.Context, sqlId string, md *sqlparser.Metadata) (*sqlparser.Metadata, error) {
+	if chain == nil {
+		return md, nil
+	}
+	var err error
+	for _, v := range chain.Interceptors() {
+		md, err = v.InterceptMetadata(ctx, sqlId, md)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return md, nil
+}
+
+func (chain *InterceptorChain) WrapExecutor(e executor.Executor) executor.Executor {
+	if chain == nil {
+		return e
+	}
+	interceptors := chain.Interceptors()
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		e = interceptors[i].WrapExecutor(e)
+	}
+	return e
+}
+
+func (chain *InterceptorChain) InterceptResult(ctx context.Context, sqlId string, md *sqlparser.Metadata, bean interface{}, err error) error {
+	if chain == nil {
+		return err
+	}
+	interceptors := chain.Interceptors()
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		err = interceptors[i].InterceptResult(ctx, sqlId, md, bean, err)
+	}
+	return err
+}
+
+// WithSqlId 将执行的sql id保存到context中
+func WithSqlId(ctx context.Context, sqlId string) context.Context {
+	return context.WithValue(ctx, sqlIdKey{}, sqlId)
+}
+
+// SqlId 获得context中正在执行的sql id，用于在包装的执行器中识别语句
+func SqlId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if v, ok := ctx.Value(sqlIdKey{}).(string); ok {
+		return v
+	}
+	return ""
+}
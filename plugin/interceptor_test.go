@@ -0,0 +1,51 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plugin
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestInterceptorChainConcurrentAdd(t *testing.T) {
+	chain := NewInterceptorChain()
+	snapshot := NewInterceptorChain(BaseInterceptor{})
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			chain.Add(BaseInterceptor{})
+		}()
+		go func() {
+			defer wg.Done()
+			NewInterceptorChain(chain.Interceptors()...).WrapExecutor(nil)
+		}()
+	}
+	wg.Wait()
+	if chain.Len() != 10 {
+		t.Fatalf("expect 10 interceptors, get %d", chain.Len())
+	}
+
+	//已获得的拦截器列表不受之后添加的影响
+	list := snapshot.Interceptors()
+	snapshot.Add(BaseInterceptor{})
+	if len(list) != 1 || snapshot.Len() != 2 {
+		t.Fatalf("expect copy on add, get %d %d", len(list), snapshot.Len())
+	}
+}
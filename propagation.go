@@ -0,0 +1,265 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gobatis
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/acmestack/gobatis/cache"
+	"github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/logging"
+	"github.com/acmestack/gobatis/session"
+)
+
+type Propagation = session.Propagation
+
+const (
+	PropagationRequired     = session.PropagationRequired
+	PropagationSupports     = session.PropagationSupports
+	PropagationMandatory    = session.PropagationMandatory
+	PropagationRequiresNew  = session.PropagationRequiresNew
+	PropagationNotSupported = session.PropagationNotSupported
+	PropagationNever        = session.PropagationNever
+	PropagationNested       = session.PropagationNested
+)
+
+const (
+	savepointPrefix = "gobatis_savepoint_"
+)
+
+// TxOptions 事务选项
+type TxOptions struct {
+	// 传播行为，默认为PropagationRequired
+	Propagation Propagation
+	// 隔离级别，默认为数据库的默认隔离级别
+	Isolation sql.IsolationLevel
+	// 是否为只读事务
+	ReadOnly bool
+	// 事务超时时间，大于0时超时后事务将被回滚
+	Timeout time.Duration
+}
+
+// txStatus Session当前事务的状态，加入该事务的Tx调用共享同一个txStatus
+type txStatus struct {
+	// 加入事务的Tx调用返回错误或panic时标记为只能回滚
+	rollbackOnly bool
+	// 嵌套事务保存点计数，用于生成保存点名称
+	savepointIndex int
+	// 事务中执行过修改语句的namespace缓存，事务结束时清空
+	flushCaches []cache.Cache
+}
+
+// TxWithPropagation 使用指定的传播行为执行事务
+// 返回nil则提交，返回error回滚
+// 抛出异常错误触发回滚
+// 挂起当前事务时（PropagationRequiresNew、PropagationNotSupported），txFunc的参数为新的Session，
+// 需使用该参数执行语句
+func (session *Session) TxWithPropagation(propagation Propagation, txFunc func(session *Session) error) error {
+	return session.propagate(propagation, nil, txFunc)
+}
+
+// TxWithOptions 使用事务选项执行事务
+// ctx为事务使用的context，在txFunc中创建的语句都将使用该context，为nil时使用Session的context
+// opts为nil时使用默认选项：PropagationRequired，数据库默认的隔离级别，非只读，不设置超时
+// 加入已存在的事务时，隔离级别和只读选项不生效
+func (session *Session) TxWithOptions(ctx context.Context, opts *TxOptions, txFunc func(session *Session) error) error {
+	if ctx == nil {
+		ctx = session.ctx
+	}
+	if opts == nil {
+		opts = &TxOptions{}
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	originCtx := session.ctx
+	session.ctx = ctx
+	defer func() {
+		session.ctx = originCtx
+	}()
+
+	txOpts := &sql.TxOptions{
+		Isolation: opts.Isolation,
+		ReadOnly:  opts.ReadOnly,
+	}
+	return session.propagate(opts.Propagation, txOpts, txFunc)
+}
+
+func (session *Session) propagate(propagation Propagation, txOpts *sql.TxOptions, txFunc func(session *Session) error) error {
+	switch propagation {
+	case PropagationRequired:
+		if session.tx != nil {
+			return session.joinTx(txFunc)
+		}
+		return session.newTx(txOpts, txFunc)
+	case PropagationSupports:
+		if session.tx != nil {
+			return session.joinTx(txFunc)
+		}
+		return txFunc(session)
+	case PropagationMandatory:
+		if session.tx == nil {
+			return errors.TransactionNotExist
+		}
+		return session.joinTx(txFunc)
+	case PropagationRequiresNew:
+		if session.tx != nil {
+			return session.suspend(func(sess *Session) error {
+				return sess.newTx(txOpts, txFunc)
+			})
+		}
+		return session.newTx(txOpts, txFunc)
+	case PropagationNotSupported:
+		if session.tx != nil {
+			return session.suspend(txFunc)
+		}
+		return txFunc(session)
+	case PropagationNever:
+		if session.tx != nil {
+			return errors.TransactionExist
+		}
+		return txFunc(session)
+	case PropagationNested:
+		if session.tx != nil {
+			return session.nestedTx(txFunc)
+		}
+		return session.newTx(txOpts, txFunc)
+	}
+	return errors.PropagationNotSupport
+}
+
+// newTx 开启新的事务
+func (session *Session) newTx(txOpts *sql.TxOptions, txFunc func(session *Session) error) (err error) {
+	e1 := session.session.Begin(session.ctx, txOpts)
+	if e1 != nil {
+		return e1
+	}
+	status := &txStatus{}
+	session.tx = status
+	defer func(err *error) {
+		session.tx = nil
+		status.clearCaches()
+		if r := recover(); r != nil {
+			*err = session.session.Rollback()
+			panic(r)
+		}
+	}(&err)
+
+	if fnErr := txFunc(session); fnErr != nil {
+		e := session.session.Rollback()
+		if e != nil {
+			session.log(logging.WARN, "Rollback error: %v , business error: %v\n", e, fnErr)
+		}
+		return fnErr
+	}
+
+	if status.rollbackOnly {
+		e := session.session.Rollback()
+		if e != nil {
+			session.log(logging.WARN, "Rollback error: %v\n", e)
+		}
+		return errors.TransactionRollbackOnly
+	}
+	return session.session.Commit()
+}
+
+// joinTx 加入当前事务，返回错误或panic时将当前事务标记为只能回滚
+func (session *Session) joinTx(txFunc func(session *Session) error) error {
+	status := session.tx
+	defer func() {
+		if r := recover(); r != nil {
+			status.rollbackOnly = true
+			panic(r)
+		}
+	}()
+
+	if fnErr := txFunc(session); fnErr != nil {
+		status.rollbackOnly = true
+		return fnErr
+	}
+	return nil
+}
+
+// Nested 在嵌套事务中执行，传播行为为PropagationNested
+// 当前存在事务时创建保存点，txFunc返回错误或panic时只回滚到保存点，不影响外层事务；
+// 当前没有事务时与Tx相同
+func (session *Session) Nested(txFunc func(session *Session) error) error {
+	return session.TxWithPropagation(PropagationNested, txFunc)
+}
+
+// nestedTx 使用保存点在当前事务中开启嵌套事务
+func (session *Session) nestedTx(txFunc func(session *Session) error) (err error) {
+	status := session.tx
+	status.savepointIndex++
+	name := fmt.Sprintf("%s%d", savepointPrefix, status.savepointIndex)
+	e1 := session.session.Savepoint(name)
+	if e1 != nil {
+		return e1
+	}
+	//保存点之后加入事务的Tx失败时标记的rollbackOnly在回滚到保存点后恢复
+	rollbackOnly := status.rollbackOnly
+	defer func(err *error) {
+		if r := recover(); r != nil {
+			*err = session.rollbackToSavepoint(name, rollbackOnly)
+			panic(r)
+		}
+	}(&err)
+
+	if fnErr := txFunc(session); fnErr != nil {
+		e := session.rollbackToSavepoint(name, rollbackOnly)
+		if e != nil {
+			session.log(logging.WARN, "Rollback to savepoint error: %v , business error: %v\n", e, fnErr)
+		}
+		return fnErr
+	}
+	return session.session.Release(name)
+}
+
+// rollbackToSavepoint 回滚到保存点并释放，成功时恢复创建保存点时的rollbackOnly，回滚失败时外层事务只能回滚
+func (session *Session) rollbackToSavepoint(name string, rollbackOnly bool) error {
+	err := session.session.RollbackTo(name)
+	if err != nil {
+		session.tx.rollbackOnly = true
+		return err
+	}
+	session.tx.rollbackOnly = rollbackOnly
+	return session.session.Release(name)
+}
+
+// suspend 挂起当前事务，使用新的Session执行
+func (session *Session) suspend(fn func(sess *Session) error) error {
+	sess := &Session{
+		ctx:           session.ctx,
+		log:           session.log,
+		session:       createSqlSession(session.factory, session.interceptors),
+		driver:        session.driver,
+		ParserFactory: session.ParserFactory,
+		factory:       session.factory,
+		interceptors:  session.interceptors,
+		config:        session.config,
+	}
+	defer sess.session.Close(false)
+
+	return fn(sess)
+}
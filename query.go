@@ -0,0 +1,191 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gobatis
+
+import (
+	"reflect"
+
+	"github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/parsing"
+	"github.com/acmestack/gobatis/parsing/sqlparser"
+	"github.com/acmestack/gobatis/reflection"
+)
+
+// QueryList 执行sqlId对应的查询，返回所有结果，T可以为struct的指针
+func QueryList[T any](sess *Session, sqlId string, params ...interface{}) ([]T, error) {
+	var ret []T
+	t := reflect.TypeOf(ret).Elem()
+	if t.Kind() != reflect.Ptr {
+		err := sess.Select(sqlId).Param(params...).Result(&ret)
+		if err != nil {
+			return nil, err
+		}
+		return ret, nil
+	}
+	//结果不支持指针的slice，使用元素类型查询后取地址
+	list := reflect.New(reflect.SliceOf(t.Elem()))
+	err := sess.Select(sqlId).Param(params...).Result(list.Interface())
+	if err != nil {
+		return nil, err
+	}
+	list = list.Elem()
+	for i := 0; i < list.Len(); i++ {
+		ret = append(ret, list.Index(i).Addr().Interface().(T))
+	}
+	return ret, nil
+}
+
+// QueryOne 执行sqlId对应的查询，返回第一个结果，没有结果时返回errors.ResultSelectEmptyValue
+// 读取第一行后即停止，语句配置了resultMap时需要合并多行数据，使用全部结果
+func QueryOne[T any](sess *Session, sqlId string, params ...interface{}) (T, error) {
+	var ret T
+	if stmt := findStatement(sess.config, sqlId); stmt != nil && stmt.ResultMap != nil {
+		list, err := QueryList[T](sess, sqlId, params...)
+		if err != nil {
+			return ret, err
+		}
+		if len(list) == 0 {
+			return ret, errors.ResultSelectEmptyValue
+		}
+		return list[0], nil
+	}
+
+	t := reflect.TypeOf(&ret).Elem()
+	bean := reflect.ValueOf(&ret)
+	if t.Kind() == reflect.Ptr {
+		bean = reflect.New(t.Elem())
+	}
+	found := false
+	err := sess.Select(sqlId).Param(params...).Iterate(bean.Interface(), func(idx int64, v interface{}) bool {
+		found = true
+		return true
+	})
+	if err != nil {
+		return ret, err
+	}
+	if !found {
+		return ret, errors.ResultSelectEmptyValue
+	}
+	if t.Kind() == reflect.Ptr {
+		ret = bean.Interface().(T)
+	}
+	return ret, nil
+}
+
+// QueryMap 执行sqlId对应的查询，使用mapKey列的值作为key，每行数据转换为V作为value，
+// V为简单类型时使用mapKey以外的列作为value，mapKey以外的列不是一列时返回错误，如：
+// SELECT username, count(*) AS num FROM test_table GROUP BY username
+// 使用QueryMap[string, int](sess, sqlId, "username")获得每个用户名的数量
+// 不支持resultMap，key重复时保留最后一行
+func QueryMap[K comparable, V any](sess *Session, sqlId string, mapKey string, params ...interface{}) (map[K]V, error) {
+	ret := map[K]V{}
+	var row map[string]interface{}
+	var convErr error
+	err := sess.Select(sqlId).Param(params...).Iterate(&row, func(idx int64, bean interface{}) bool {
+		var k K
+		kv, ok := row[mapKey]
+		if !ok {
+			convErr = errors.ResultNameNotFound
+			return true
+		}
+		if !reflection.SetValue(reflect.ValueOf(&k).Elem(), kv) {
+			convErr = errors.ResultSetValueFailed
+			return true
+		}
+		var v V
+		if convErr = convertRow(sess.config, row, mapKey, &v); convErr != nil {
+			return true
+		}
+		ret[k] = v
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if convErr != nil {
+		return nil, convErr
+	}
+	return ret, nil
+}
+
+// convertRow 将一行数据转换为bean
+func convertRow(config *Configuration, row map[string]interface{}, mapKey string, bean interface{}) error {
+	obj, err := config.ParseObject(bean)
+	if err != nil {
+		return err
+	}
+	if obj.CanAddValue() {
+		return errors.ResultMapTypeNotSupport
+	}
+	if !obj.CanSetField() {
+		if len(row) != 2 {
+			return errors.ResultMapValueColumnError
+		}
+		for name, v := range row {
+			if name != mapKey {
+				obj.SetValue(reflect.ValueOf(v))
+			}
+		}
+		return nil
+	}
+	if v := obj.GetValue(); v.Kind() == reflect.Map {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	for name, v := range row {
+		obj.SetField(name, v)
+	}
+	return nil
+}
+
+// Exec 执行sqlId对应的insert、update、delete或其他语句，返回影响的行数
+func Exec(sess *Session, sqlId string, params ...interface{}) (int64, error) {
+	var runner Runner
+	switch statementAction(sess.config, sqlId) {
+	case sqlparser.INSERT:
+		runner = sess.Insert(sqlId)
+	case sqlparser.UPDATE:
+		runner = sess.Update(sqlId)
+	case sqlparser.DELETE:
+		runner = sess.Delete(sqlId)
+	default:
+		runner = sess.Exec(sqlId)
+	}
+	var ret int64
+	err := runner.Param(params...).Result(&ret)
+	return ret, err
+}
+
+// statementAction 获得mapper中语句的类型，不是mapper中的语句时返回空字符串
+func statementAction(config *Configuration, sqlId string) string {
+	if stmt := findStatement(config, sqlId); stmt != nil {
+		return stmt.Action
+	}
+	return ""
+}
+
+// findStatement 获得mapper中语句的配置，不是mapper中的语句时返回nil
+func findStatement(config *Configuration, sqlId string) *parsing.Statement {
+	parser, ok := config.FindSqlParser(sqlId)
+	if !ok {
+		return nil
+	}
+	if d, ok := parser.(*parsing.DynamicData); ok {
+		return d.Statement
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reflection
+
+import (
+	"reflect"
+
+	"github.com/acmestack/gobatis/common"
+)
+
+// IterObject 逐行反序列化的对象，每读取一行数据即反序列化到bean中并回调IterFunc，
+// 不会将结果集保存在内存中
+type IterObject struct {
+	bean     interface{}
+	elem     Object
+	iterFunc common.IterFunc
+	index    int64
+	broken   bool
+}
+
+// NewIterObject 创建迭代对象
+//
+//	bean: 必须为指针，每行数据反序列化后的值保存在bean中
+//	elem: bean对应的Object
+//	iterFunc: 每行数据的回调，返回true则停止迭代
+func NewIterObject(bean interface{}, elem Object, iterFunc common.IterFunc) *IterObject {
+	return &IterObject{
+		bean:     bean,
+		elem:     elem,
+		iterFunc: iterFunc,
+	}
+}
+
+func (iterObj *IterObject) Kind() int {
+	return ObjectCustom
+}
+
+func (iterObj *IterObject) New() Object {
+	return NewIterObject(iterObj.bean, iterObj.elem, iterObj.iterFunc)
+}
+
+// NewElem 重置bean的值并返回bean对应的Object，用于反序列化下一行数据
+func (iterObj *IterObject) NewElem() Object {
+	v := iterObj.elem.GetValue()
+	if v.Kind() == reflect.Map {
+		v.Set(reflect.MakeMap(v.Type()))
+	} else {
+		v.Set(reflect.Zero(v.Type()))
+	}
+	return iterObj.elem
+}
+
+func (iterObj *IterObject) SetField(name string, v interface{}) {
+}
+
+// AddValue 回调IterFunc，值已保存在bean中
+func (iterObj *IterObject) AddValue(v reflect.Value) {
+	if iterObj.broken {
+		return
+	}
+	iterObj.broken = iterObj.iterFunc(iterObj.index, iterObj.bean)
+	iterObj.index++
+}
+
+func (iterObj *IterObject) GetClassName() string {
+	return iterObj.elem.GetClassName()
+}
+
+func (iterObj *IterObject) CanSetField() bool {
+	return false
+}
+
+func (iterObj *IterObject) CanAddValue() bool {
+	return true
+}
+
+func (iterObj *IterObject) NewValue() reflect.Value {
+	return iterObj.elem.NewValue()
+}
+
+func (iterObj *IterObject) CanSet(v reflect.Value) bool {
+	return false
+}
+
+func (iterObj *IterObject) SetValue(v reflect.Value) {
+}
+
+func (iterObj *IterObject) GetValue() reflect.Value {
+	return iterObj.elem.GetValue()
+}
+
+func (iterObj *IterObject) ResetValue(v reflect.Value) {
+	iterObj.elem.ResetValue(v)
+}
+
+// Broken 是否已打断迭代
+func (iterObj *IterObject) Broken() bool {
+	return iterObj.broken
+}
+
+// Count 已迭代的行数
+func (iterObj *IterObject) Count() int64 {
+	return iterObj.index
+}
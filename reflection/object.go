@@ -321,7 +321,8 @@ func (mapInfo *MapInfo) New() Object {
 		ClassName: mapInfo.ClassName,
 		ElemType:  mapInfo.ElemType,
 	}
-	ret.Value = reflect.New(mapInfo.Type).Elem()
+	ret.Type = mapInfo.Type
+	ret.Value = reflect.MakeMap(mapInfo.Type)
 	return ret
 }
 
@@ -515,7 +516,7 @@ func GetReflectStructInfo(rt reflect.Type, rv reflect.Value) (*StructInfo, error
 		}
 
 		fieldName := rtf.Name
-		tagName := rtf.Tag.Get(common.ColumnName)
+		tagName, _ := ParseColumnTag(rtf.Tag.Get(common.ColumnName))
 		if tagName == "-" {
 			continue
 		} else if tagName != "" {
@@ -110,12 +110,21 @@ func (parser *paramParser) parseOne(parentKey string, v interface{}) {
 		for _, key := range keys {
 			if key.Kind() == reflect.String {
 				value := rv.MapIndex(key)
-				value = value.Elem()
+				if value.Kind() == reflect.Interface {
+					value = value.Elem()
+				}
+				if !value.IsValid() {
+					continue
+				}
 				if IsSimpleType(value.Type()) {
 					if !value.CanInterface() {
 						value = reflect.Indirect(value)
 					}
-					parser.ret[parentKey+key.String()] = value
+					//保存原始值，作为sql参数时驱动无法转换reflect.Value
+					parser.ret[parentKey+key.String()] = value.Interface()
+				} else if value.CanInterface() {
+					//集合等复杂类型保存原始值，用于foreach及test表达式
+					parser.ret[parentKey+key.String()] = value.Interface()
 				}
 			}
 		}
@@ -0,0 +1,571 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reflection
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/acmestack/gobatis/errors"
+)
+
+// ResultProperty 字段与列的映射
+type ResultProperty struct {
+	// Property 结构体字段名
+	Property string
+	// Column 列名
+	Column string
+}
+
+// NestedResult 嵌套的结果映射，association对应结构体或结构体指针字段，collection对应slice字段
+type NestedResult struct {
+	Property string
+	// ColumnPrefix 列名前缀，嵌套映射中的列名都会加上该前缀，同时按前缀自动映射未配置的列
+	ColumnPrefix string
+	ResultMap    *ResultMap
+}
+
+// Discriminator 根据列的值选择使用的结果映射
+type Discriminator struct {
+	Column string
+	Cases  map[string]*ResultMap
+}
+
+// ResultMap 解析后的resultMap，用于将查询结果映射到嵌套的结构体中
+type ResultMap struct {
+	Id string
+	// Ids 唯一标识结果的列，用于对一对多查询的结果分组
+	Ids           []ResultProperty
+	Results       []ResultProperty
+	Associations  []*NestedResult
+	Collections   []*NestedResult
+	Discriminator *Discriminator
+}
+
+// HasNested 是否包含嵌套的结果映射
+func (rm *ResultMap) HasNested() bool {
+	return rm.hasNested(0)
+}
+
+func (rm *ResultMap) hasNested(depth int) bool {
+	if len(rm.Associations) > 0 || len(rm.Collections) > 0 {
+		return true
+	}
+	if rm.Discriminator != nil && depth < 16 {
+		for _, v := range rm.Discriminator.Cases {
+			if v.hasNested(depth + 1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve 根据discriminator列的值获得实际使用的结果映射
+func (rm *ResultMap) resolve(row map[string]interface{}, prefix string) *ResultMap {
+	ret := rm
+	//避免case之间循环引用
+	for i := 0; ret.Discriminator != nil && i < 16; i++ {
+		c, ok := ret.Discriminator.Cases[columnString(row[prefix+ret.Discriminator.Column])]
+		if !ok {
+			break
+		}
+		ret = c
+	}
+	return ret
+}
+
+// columns 获得显式配置的列
+func (rm *ResultMap) columns() []ResultProperty {
+	ret := make([]ResultProperty, 0, len(rm.Ids)+len(rm.Results))
+	ret = append(ret, rm.Ids...)
+	return append(ret, rm.Results...)
+}
+
+// hasValue 判断嵌套映射的列是否有非空值，没有时不创建嵌套对象（如left join没有匹配的行）
+func (rm *ResultMap) hasValue(row map[string]interface{}, prefix string) bool {
+	props := rm.columns()
+	if len(props) == 0 {
+		for k, v := range row {
+			if strings.HasPrefix(k, prefix) && v != nil {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range props {
+		if row[prefix+p.Column] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// rowKey 计算用于分组的key，优先使用id列，没有配置id时使用所有配置的列
+func (rm *ResultMap) rowKey(row map[string]interface{}, prefix string) string {
+	props := rm.Ids
+	if len(props) == 0 {
+		props = rm.Results
+	}
+	buf := strings.Builder{}
+	if len(props) == 0 {
+		keys := make([]string, 0, len(row))
+		for k := range row {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			buf.WriteString(fmt.Sprintf("%s=%v\x00", k, row[k]))
+		}
+		return buf.String()
+	}
+	for _, p := range props {
+		buf.WriteString(fmt.Sprintf("%v\x00", row[prefix+p.Column]))
+	}
+	return buf.String()
+}
+
+// nestedColumns 收集嵌套映射中显式配置的列，这些列不参与顶层的自动映射
+func (rm *ResultMap) nestedColumns(prefix string, ret map[string]bool, depth int) {
+	if depth > 16 {
+		return
+	}
+	nested := append(append([]*NestedResult{}, rm.Associations...), rm.Collections...)
+	for _, n := range nested {
+		p := prefix + n.ColumnPrefix
+		for _, c := range n.ResultMap.columns() {
+			ret[p+c.Column] = true
+		}
+		n.ResultMap.nestedColumns(p, ret, depth+1)
+	}
+	if rm.Discriminator != nil {
+		for _, c := range rm.Discriminator.Cases {
+			c.nestedColumns(prefix, ret, depth+1)
+		}
+	}
+}
+
+// checkType 检查结果类型与嵌套映射是否匹配，association对应的字段必须为结构体或结构体指针，
+// collection对应的字段必须为结构体（指针）的slice，结果类型中不存在的字段不做处理
+func (rm *ResultMap) checkType(rt reflect.Type, depth int) error {
+	if depth > 16 {
+		return nil
+	}
+	for _, a := range rm.Associations {
+		f, ok := rt.FieldByName(a.Property)
+		if !ok {
+			continue
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct {
+			return errors.ResultMapPropertyTypeError
+		}
+		if err := a.ResultMap.checkType(ft, depth+1); err != nil {
+			return err
+		}
+	}
+	for _, c := range rm.Collections {
+		f, ok := rt.FieldByName(c.Property)
+		if !ok {
+			continue
+		}
+		if f.Type.Kind() != reflect.Slice {
+			return errors.ResultMapPropertyTypeError
+		}
+		et := f.Type.Elem()
+		if et.Kind() == reflect.Ptr {
+			et = et.Elem()
+		}
+		if et.Kind() != reflect.Struct {
+			return errors.ResultMapPropertyTypeError
+		}
+		if err := c.ResultMap.checkType(et, depth+1); err != nil {
+			return err
+		}
+	}
+	if rm.Discriminator != nil {
+		for _, c := range rm.Discriminator.Cases {
+			if err := c.checkType(rt, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func columnString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(s)
+	case string:
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// resultNode 已创建的结果对象，slice中的元素在追加后地址可能变化，所以通过函数获得
+type resultNode struct {
+	value       func() reflect.Value
+	resultMap   *ResultMap
+	assocs      map[string]*resultNode
+	collections map[string]map[string]*resultNode
+}
+
+func newResultNode(rm *ResultMap, value func() reflect.Value) *resultNode {
+	return &resultNode{
+		value:       value,
+		resultMap:   rm,
+		assocs:      map[string]*resultNode{},
+		collections: map[string]map[string]*resultNode{},
+	}
+}
+
+// ResultMapObject 使用ResultMap反序列化的对象，结果必须为结构体或结构体（指针）的slice
+type ResultMapObject struct {
+	resultMap *ResultMap
+	dest      reflect.Value
+	isSlice   bool
+	// 不参与顶层自动映射的列
+	excludes map[string]bool
+	fieldMap map[reflect.Type]map[string]string
+	row      *resultRow
+	roots    map[string]*resultNode
+	count    int
+}
+
+// NewResultMapObject 创建使用ResultMap反序列化的对象，bean必须为结构体或结构体（指针）slice的指针
+// 嵌套映射对应的字段类型不匹配时返回错误
+func NewResultMapObject(rm *ResultMap, bean interface{}) (*ResultMapObject, error) {
+	rv := reflect.ValueOf(bean)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, errors.ResultIsnotPointer
+	}
+	rv = rv.Elem()
+	ret := &ResultMapObject{
+		resultMap: rm,
+		dest:      rv,
+		excludes:  map[string]bool{},
+		fieldMap:  map[reflect.Type]map[string]string{},
+		roots:     map[string]*resultNode{},
+	}
+	rt := rv.Type()
+	switch rv.Kind() {
+	case reflect.Struct:
+	case reflect.Slice:
+		rt = rt.Elem()
+		if rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		if rt.Kind() != reflect.Struct {
+			return nil, errors.ResultMapTypeNotSupport
+		}
+		ret.isSlice = true
+	default:
+		return nil, errors.ResultMapTypeNotSupport
+	}
+	if err := rm.checkType(rt, 0); err != nil {
+		return nil, err
+	}
+	rm.nestedColumns("", ret.excludes, 0)
+	return ret, nil
+}
+
+func (obj *ResultMapObject) Kind() int {
+	return ObjectCustom
+}
+
+func (obj *ResultMapObject) New() Object {
+	return &ResultMapObject{
+		resultMap: obj.resultMap,
+		dest:      reflect.New(obj.dest.Type()).Elem(),
+		isSlice:   obj.isSlice,
+		excludes:  obj.excludes,
+		fieldMap:  map[reflect.Type]map[string]string{},
+		roots:     map[string]*resultNode{},
+	}
+}
+
+// NewElem 获得收集一行数据的对象
+func (obj *ResultMapObject) NewElem() Object {
+	obj.row = &resultRow{values: map[string]interface{}{}}
+	return obj.row
+}
+
+func (obj *ResultMapObject) SetField(name string, v interface{}) {
+}
+
+// AddValue 将收集的一行数据按ResultMap映射到结果中
+func (obj *ResultMapObject) AddValue(v reflect.Value) {
+	if obj.row == nil {
+		return
+	}
+	row := obj.row
+	obj.row = nil
+	obj.addRow(row)
+}
+
+func (obj *ResultMapObject) GetClassName() string {
+	return GetTypeClassName(obj.dest.Type())
+}
+
+func (obj *ResultMapObject) CanSetField() bool {
+	return false
+}
+
+func (obj *ResultMapObject) CanAddValue() bool {
+	return true
+}
+
+func (obj *ResultMapObject) NewValue() reflect.Value {
+	return reflect.New(obj.dest.Type()).Elem()
+}
+
+func (obj *ResultMapObject) CanSet(v reflect.Value) bool {
+	return false
+}
+
+func (obj *ResultMapObject) SetValue(v reflect.Value) {
+}
+
+func (obj *ResultMapObject) GetValue() reflect.Value {
+	return obj.dest
+}
+
+func (obj *ResultMapObject) ResetValue(v reflect.Value) {
+	obj.dest = v
+}
+
+func (obj *ResultMapObject) addRow(row *resultRow) {
+	rm := obj.resultMap.resolve(row.values, "")
+	key := strconv.Itoa(obj.count)
+	//包含嵌套映射时按id分组，否则每行对应一个结果
+	if rm.HasNested() {
+		key = rm.rowKey(row.values, "")
+	}
+	obj.count++
+
+	node, ok := obj.roots[key]
+	if !ok {
+		if obj.isSlice {
+			idx := appendElem(obj.dest)
+			dest := obj.dest
+			node = newResultNode(rm, func() reflect.Value {
+				return indirectValue(dest.Index(idx))
+			})
+		} else {
+			//结果为结构体时只使用第一个结果
+			if len(obj.roots) > 0 {
+				return
+			}
+			dest := obj.dest
+			node = newResultNode(rm, func() reflect.Value {
+				return dest
+			})
+		}
+		obj.roots[key] = node
+		obj.autoMapping(node.value(), row, "", obj.excludes)
+		obj.setProperties(node.value(), rm, row, "")
+	}
+	obj.setNested(node, row, "")
+}
+
+// setNested 处理association及collection
+func (obj *ResultMapObject) setNested(node *resultNode, row *resultRow, prefix string) {
+	rm := node.resultMap
+	for _, a := range rm.Associations {
+		p := prefix + a.ColumnPrefix
+		if !a.ResultMap.hasValue(row.values, p) {
+			continue
+		}
+		child, ok := node.assocs[a.Property]
+		if !ok {
+			if !node.value().FieldByName(a.Property).IsValid() {
+				continue
+			}
+			property := a.Property
+			parent := node
+			child = newResultNode(a.ResultMap.resolve(row.values, p), func() reflect.Value {
+				return indirectValue(parent.value().FieldByName(property))
+			})
+			node.assocs[a.Property] = child
+			if a.ColumnPrefix != "" {
+				obj.autoMapping(child.value(), row, p, nil)
+			}
+			obj.setProperties(child.value(), child.resultMap, row, p)
+		}
+		obj.setNested(child, row, p)
+	}
+
+	for _, c := range rm.Collections {
+		p := prefix + c.ColumnPrefix
+		if !c.ResultMap.hasValue(row.values, p) {
+			continue
+		}
+		sub := c.ResultMap.resolve(row.values, p)
+		key := sub.rowKey(row.values, p)
+		group, ok := node.collections[c.Property]
+		if !ok {
+			group = map[string]*resultNode{}
+			node.collections[c.Property] = group
+		}
+		child, ok := group[key]
+		if !ok {
+			field := node.value().FieldByName(c.Property)
+			if !field.IsValid() {
+				continue
+			}
+			idx := appendElem(field)
+			property := c.Property
+			parent := node
+			child = newResultNode(sub, func() reflect.Value {
+				return indirectValue(parent.value().FieldByName(property).Index(idx))
+			})
+			group[key] = child
+			if c.ColumnPrefix != "" {
+				obj.autoMapping(child.value(), row, p, nil)
+			}
+			obj.setProperties(child.value(), sub, row, p)
+		}
+		obj.setNested(child, row, p)
+	}
+}
+
+// autoMapping 按结构体的column tag映射带有前缀的列
+func (obj *ResultMapObject) autoMapping(v reflect.Value, row *resultRow, prefix string, excludes map[string]bool) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	fields := obj.fields(v.Type())
+	for _, col := range row.columns {
+		if excludes[col] || !strings.HasPrefix(col, prefix) {
+			continue
+		}
+		if name, ok := fields[col[len(prefix):]]; ok {
+			SetValue(v.FieldByName(name), row.values[col])
+		}
+	}
+}
+
+func (obj *ResultMapObject) setProperties(v reflect.Value, rm *ResultMap, row *resultRow, prefix string) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for _, p := range rm.columns() {
+		f := v.FieldByName(p.Property)
+		if f.IsValid() {
+			SetValue(f, row.values[prefix+p.Column])
+		}
+	}
+}
+
+func (obj *ResultMapObject) fields(rt reflect.Type) map[string]string {
+	if ret, ok := obj.fieldMap[rt]; ok {
+		return ret
+	}
+	var ret map[string]string
+	if info, err := GetReflectStructInfo(rt, reflect.Value{}); err == nil {
+		ret = info.FieldNameMap
+	}
+	obj.fieldMap[rt] = ret
+	return ret
+}
+
+// appendElem 在slice中追加零值元素，返回元素的索引
+func appendElem(slice reflect.Value) int {
+	slice.Set(reflect.Append(slice, reflect.New(slice.Type().Elem()).Elem()))
+	return slice.Len() - 1
+}
+
+// indirectValue 获得指针指向的值，指针为nil时创建新的对象
+func indirectValue(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return v.Elem()
+	}
+	return v
+}
+
+// resultRow 收集一行数据
+type resultRow struct {
+	columns []string
+	values  map[string]interface{}
+}
+
+func (row *resultRow) Kind() int {
+	return ObjectCustom
+}
+
+func (row *resultRow) New() Object {
+	return &resultRow{values: map[string]interface{}{}}
+}
+
+func (row *resultRow) NewElem() Object {
+	return row
+}
+
+func (row *resultRow) SetField(name string, v interface{}) {
+	if _, ok := row.values[name]; !ok {
+		row.columns = append(row.columns, name)
+	}
+	row.values[name] = v
+}
+
+func (row *resultRow) AddValue(v reflect.Value) {
+}
+
+func (row *resultRow) GetClassName() string {
+	return "ResultRow"
+}
+
+func (row *resultRow) CanSetField() bool {
+	return true
+}
+
+func (row *resultRow) CanAddValue() bool {
+	return false
+}
+
+func (row *resultRow) NewValue() reflect.Value {
+	return reflect.Value{}
+}
+
+func (row *resultRow) CanSet(v reflect.Value) bool {
+	return false
+}
+
+func (row *resultRow) SetValue(v reflect.Value) {
+}
+
+func (row *resultRow) GetValue() reflect.Value {
+	return reflect.Value{}
+}
+
+func (row *resultRow) ResetValue(v reflect.Value) {
+}
@@ -0,0 +1,110 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reflection
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/acmestack/gobatis/common"
+	"github.com/acmestack/gobatis/errors"
+)
+
+// TableInfo 模型对应的表及列
+type TableInfo struct {
+	// 表名
+	Name string
+	// 模型名称，作为参数名的前缀，如#{TestTable.id}中的TestTable
+	ModelName string
+	Columns   []ColumnInfo
+}
+
+type ColumnInfo struct {
+	Name          string
+	FieldName     string
+	PrimaryKey    bool
+	AutoIncrement bool
+}
+
+// ParseColumnTag 解析column tag，返回列名及选项，如`column:"id,pk,auto"`
+func ParseColumnTag(tag string) (string, []string) {
+	parts := strings.Split(tag, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts[0], parts[1:]
+}
+
+// GetTableInfo 解析struct对应的表信息：
+// 1、表名使用gobatis.TableName类型字段的tag，tag为空时使用字段名；没有该字段时使用struct名称
+// 2、列名与GetStructInfo的规则一致，未导出的字段被忽略
+// 3、column tag包含pk选项的列为主键，包含auto选项的列为自增列
+func GetTableInfo(rt reflect.Type) (*TableInfo, error) {
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct {
+		return nil, errors.ParseObjectNotStruct
+	}
+	ret := &TableInfo{
+		Name:      rt.Name(),
+		ModelName: rt.Name(),
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		rtf := rt.Field(i)
+		if rtf.Type == modelNameType {
+			ret.Name = rtf.Name
+			if rtf.Tag != "" {
+				ret.Name = string(rtf.Tag)
+			}
+			continue
+		}
+		if rtf.PkgPath != "" || rtf.Tag == "-" {
+			continue
+		}
+		name, options := ParseColumnTag(rtf.Tag.Get(common.ColumnName))
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = rtf.Name
+		}
+		column := ColumnInfo{Name: name, FieldName: rtf.Name}
+		for _, o := range options {
+			switch o {
+			case common.ColumnPrimaryKey:
+				column.PrimaryKey = true
+			case common.ColumnAutoIncrement:
+				column.AutoIncrement = true
+			}
+		}
+		ret.Columns = append(ret.Columns, column)
+	}
+	return ret, nil
+}
+
+// PrimaryKeys 获得主键列
+func (tableInfo *TableInfo) PrimaryKeys() []ColumnInfo {
+	var ret []ColumnInfo
+	for _, c := range tableInfo.Columns {
+		if c.PrimaryKey {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,293 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gobatis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/parsing/expr"
+	"github.com/acmestack/gobatis/reflection"
+)
+
+const (
+	repositoryNamespacePrefix = "gobatis.repository"
+
+	repositoryInsert            = "insert"
+	repositoryBatchInsert       = "batchInsert"
+	repositoryUpdateById        = "updateById"
+	repositoryUpdateNonZeroById = "updateNonZeroById"
+	repositoryDeleteById        = "deleteById"
+	repositorySelectById        = "selectById"
+	repositorySelectByExample   = "selectByExample"
+)
+
+// identifierQuote 引用表名及列名时前后使用的字符
+type identifierQuote struct {
+	open  string
+	close string
+}
+
+// 数据库驱动引用表名及列名使用的字符，未注册的驱动不引用
+// postgres引用后表名及列名区分大小写，无法匹配未引用创建的大写名称，因此默认不引用
+var gIdentifierQuoteMap = map[string]identifierQuote{
+	"mysql":   {"`", "`"},   //mysql
+	"sqlite3": {"\"", "\""}, //sqlite
+}
+
+// 注册与创建Session时的查询可能并发执行
+var gIdentifierQuoteLock sync.RWMutex
+
+// RegisterIdentifierQuote 注册数据库驱动引用表名及列名前后使用的字符，如sqlserver的"["及"]"，返回是否覆盖了已有的注册
+// 只对之后首次生成语句的Repository生效
+func RegisterIdentifierQuote(driverName, open, close string) bool {
+	gIdentifierQuoteLock.Lock()
+	defer gIdentifierQuoteLock.Unlock()
+	_, ok := gIdentifierQuoteMap[driverName]
+	gIdentifierQuoteMap[driverName] = identifierQuote{open: open, close: close}
+	return ok
+}
+
+// SelectIdentifierQuote 获得数据库驱动引用表名及列名前后使用的字符，未注册时返回空字符串
+func SelectIdentifierQuote(driverName string) (string, string) {
+	gIdentifierQuoteLock.RLock()
+	defer gIdentifierQuoteLock.RUnlock()
+	v := gIdentifierQuoteMap[driverName]
+	return v.open, v.close
+}
+
+// 生成的语句注册到Configuration时加锁，避免多个Repository重复注册
+var gRepositoryLock sync.Mutex
+
+// Repository 根据模型的column tag生成增删改查语句，主键使用pk选项声明，自增列使用auto选项声明，如：
+// Id int64 `column:"id,pk,auto"`
+// 语句在首次使用时按Session的Configuration及数据库驱动生成并注册，namespace为gobatis.repository.驱动名.模型类名
+type Repository[T any] struct {
+	sessionManager *SessionManager
+	className      string
+	table          *reflection.TableInfo
+	primaryKeys    []reflection.ColumnInfo
+}
+
+// NewRepository 创建模型T的Repository，T必须为包含主键的struct，
+// 并且需要包含非自增的列用于insert，包含非主键的列用于update
+// 调用方法时使用context中的Session（如Tx中的context），context中没有Session时创建新的Session
+func NewRepository[T any](sessionManager *SessionManager) (*Repository[T], error) {
+	var model T
+	rt := reflect.TypeOf(model)
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil, errors.ParseObjectNotStruct
+	}
+	table, err := reflection.GetTableInfo(rt)
+	if err != nil {
+		return nil, err
+	}
+	ret := &Repository[T]{
+		sessionManager: sessionManager,
+		className:      reflection.GetTypeClassName(rt),
+		table:          table,
+		primaryKeys:    table.PrimaryKeys(),
+	}
+	if len(ret.primaryKeys) == 0 {
+		return nil, errors.PrimaryKeyNotFound
+	}
+	//避免生成INSERT INTO t() VALUES()及UPDATE t SET WHERE
+	insertable, updatable := false, false
+	for _, c := range table.Columns {
+		insertable = insertable || !c.AutoIncrement
+		updatable = updatable || !c.PrimaryKey
+	}
+	if !insertable || !updatable {
+		return nil, errors.ModelColumnsNotEnough
+	}
+	return ret, nil
+}
+
+// Insert 插入一行数据，存在自增列时将生成的值写回model
+func (repository *Repository[T]) Insert(ctx context.Context, model *T) (int64, error) {
+	return repository.exec(ctx, repositoryInsert, model)
+}
+
+// BatchInsert 使用一条语句插入多行数据，存在自增列时将生成的值写回models
+func (repository *Repository[T]) BatchInsert(ctx context.Context, models []T) (int64, error) {
+	if len(models) == 0 {
+		return 0, nil
+	}
+	return repository.exec(ctx, repositoryBatchInsert, models)
+}
+
+// UpdateById 按主键更新所有列
+func (repository *Repository[T]) UpdateById(ctx context.Context, model T) (int64, error) {
+	return repository.exec(ctx, repositoryUpdateById, model)
+}
+
+// UpdateNonZeroById 按主键更新非零值的列，零值的判断与test表达式一致，没有需要更新的列时不执行
+func (repository *Repository[T]) UpdateNonZeroById(ctx context.Context, model T) (int64, error) {
+	rv := reflect.ValueOf(model)
+	for _, c := range repository.table.Columns {
+		if !c.PrimaryKey && expr.Truthy(rv.FieldByName(c.FieldName).Interface()) {
+			return repository.exec(ctx, repositoryUpdateNonZeroById, model)
+		}
+	}
+	return 0, nil
+}
+
+// DeleteById 按主键删除，id按主键声明的顺序传入
+func (repository *Repository[T]) DeleteById(ctx context.Context, id ...interface{}) (int64, error) {
+	if len(id) != len(repository.primaryKeys) {
+		return 0, errors.ParseSqlParamVarNumberError
+	}
+	return repository.exec(ctx, repositoryDeleteById, id...)
+}
+
+// SelectById 按主键查询，id按主键声明的顺序传入，没有结果时返回errors.ResultSelectEmptyValue
+func (repository *Repository[T]) SelectById(ctx context.Context, id ...interface{}) (*T, error) {
+	if len(id) != len(repository.primaryKeys) {
+		return nil, errors.ParseSqlParamVarNumberError
+	}
+	list, err := repository.query(ctx, repositorySelectById, id...)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, errors.ResultSelectEmptyValue
+	}
+	return &list[0], nil
+}
+
+// SelectByExample 查询所有非零值的列与example相等的数据，零值的判断与test表达式一致
+func (repository *Repository[T]) SelectByExample(ctx context.Context, example T) ([]T, error) {
+	return repository.query(ctx, repositorySelectByExample, example)
+}
+
+func (repository *Repository[T]) exec(ctx context.Context, id string, params ...interface{}) (int64, error) {
+	sess, namespace, err := repository.session(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var runner Runner
+	sqlId := namespace + "." + id
+	switch id {
+	case repositoryInsert, repositoryBatchInsert:
+		runner = sess.Insert(sqlId)
+	case repositoryDeleteById:
+		runner = sess.Delete(sqlId)
+	default:
+		runner = sess.Update(sqlId)
+	}
+	var ret int64
+	err = runner.Context(ctx).Param(params...).Result(&ret)
+	return ret, err
+}
+
+func (repository *Repository[T]) query(ctx context.Context, id string, params ...interface{}) ([]T, error) {
+	sess, namespace, err := repository.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ret []T
+	err = sess.Select(namespace + "." + id).Context(ctx).Param(params...).Result(&ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// session 获得执行使用的Session及语句的namespace，语句未注册时生成并注册
+func (repository *Repository[T]) session(ctx context.Context) (*Session, string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	sess := repository.sessionManager.contextSession(ctx)
+	namespace := fmt.Sprintf("%s.%s.%s", repositoryNamespacePrefix, sess.driver, repository.className)
+
+	gRepositoryLock.Lock()
+	defer gRepositoryLock.Unlock()
+	if _, ok := sess.config.FindSqlParser(namespace + "." + repositorySelectById); ok {
+		return sess, namespace, nil
+	}
+	open, close := SelectIdentifierQuote(sess.driver)
+	mapper := repository.mapper(namespace, open, close)
+	if err := sess.config.RegisterMapperData([]byte(mapper)); err != nil {
+		return nil, "", err
+	}
+	return sess, namespace, nil
+}
+
+// mapper 生成模型的mapper
+func (repository *Repository[T]) mapper(namespace, open, close string) string {
+	table := repository.table
+	q := func(name string) string {
+		return open + name + close
+	}
+	param := func(prefix string, c reflection.ColumnInfo) string {
+		return "#{" + prefix + table.ModelName + "." + c.Name + "}"
+	}
+
+	var columns, insertColumns, values, itemValues, sets, nonZeroSets, examples, autoKeys []string
+	for _, c := range table.Columns {
+		columns = append(columns, q(c.Name))
+		examples = append(examples, fmt.Sprintf(`<if test="{%s.%s}">AND %s = %s</if>`, table.ModelName, c.Name, q(c.Name), param("", c)))
+		if c.AutoIncrement {
+			autoKeys = append(autoKeys, c.Name)
+		} else {
+			insertColumns = append(insertColumns, q(c.Name))
+			values = append(values, param("", c))
+			itemValues = append(itemValues, param("item.", c))
+		}
+		if !c.PrimaryKey {
+			sets = append(sets, q(c.Name)+" = "+param("", c))
+			nonZeroSets = append(nonZeroSets, fmt.Sprintf(`<if test="{%s.%s}">%s = %s,</if>`, table.ModelName, c.Name, q(c.Name), param("", c)))
+		}
+	}
+	var modelKeys, idKeys []string
+	for i, c := range repository.primaryKeys {
+		modelKeys = append(modelKeys, q(c.Name)+" = "+param("", c))
+		idKeys = append(idKeys, fmt.Sprintf("%s = #{%d}", q(c.Name), i))
+	}
+	keyAttrs := ""
+	if len(autoKeys) > 0 {
+		keys := strings.Join(autoKeys, ",")
+		keyAttrs = fmt.Sprintf(` useGeneratedKeys="true" keyProperty="%s" keyColumn="%s"`, keys, keys)
+	}
+	tableName := q(table.Name)
+	selectColumns := strings.Join(columns, ", ")
+
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("<mapper namespace=\"%s\">\n", namespace))
+	b.WriteString(fmt.Sprintf("<insert id=\"%s\"%s>INSERT INTO %s(%s) VALUES(%s)</insert>\n",
+		repositoryInsert, keyAttrs, tableName, strings.Join(insertColumns, ", "), strings.Join(values, ", ")))
+	b.WriteString(fmt.Sprintf("<insert id=\"%s\"%s>INSERT INTO %s(%s) VALUES <foreach item=\"item\" collection=\"{0}\" separator=\",\">(%s)</foreach></insert>\n",
+		repositoryBatchInsert, keyAttrs, tableName, strings.Join(insertColumns, ", "), strings.Join(itemValues, ", ")))
+	b.WriteString(fmt.Sprintf("<update id=\"%s\">UPDATE %s SET %s WHERE %s</update>\n",
+		repositoryUpdateById, tableName, strings.Join(sets, ", "), strings.Join(modelKeys, " AND ")))
+	b.WriteString(fmt.Sprintf("<update id=\"%s\">UPDATE %s <set>%s</set> WHERE %s</update>\n",
+		repositoryUpdateNonZeroById, tableName, strings.Join(nonZeroSets, ""), strings.Join(modelKeys, " AND ")))
+	b.WriteString(fmt.Sprintf("<delete id=\"%s\">DELETE FROM %s WHERE %s</delete>\n",
+		repositoryDeleteById, tableName, strings.Join(idKeys, " AND ")))
+	b.WriteString(fmt.Sprintf("<select id=\"%s\">SELECT %s FROM %s WHERE %s</select>\n",
+		repositorySelectById, selectColumns, tableName, strings.Join(idKeys, " AND ")))
+	b.WriteString(fmt.Sprintf("<select id=\"%s\">SELECT %s FROM %s <where>%s</where></select>\n",
+		repositorySelectByExample, selectColumns, tableName, strings.Join(examples, "")))
+	b.WriteString("</mapper>")
+	return b.String()
+}
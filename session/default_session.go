@@ -19,6 +19,7 @@ package session
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/acmestack/gobatis/common"
@@ -101,19 +102,48 @@ func (session *DefaultSqlSession) Delete(ctx context.Context, sql string, params
 	return count, nil
 }
 
-func (session *DefaultSqlSession) Begin() error {
+func (session *DefaultSqlSession) FlushStatements(ctx context.Context) ([]executor.BatchResult, error) {
+	session.logLastSql("FlushStatements", "")
+	return executor.FlushStatements(ctx, session.executor)
+}
+
+func (session *DefaultSqlSession) Begin(ctx context.Context, opts *sql.TxOptions) error {
 	session.logLastSql("Begin", "")
-	return session.tx.Begin()
+	return session.executor.Begin(ctx, opts)
 }
 
 func (session *DefaultSqlSession) Commit() error {
 	session.logLastSql("Commit", "")
-	return session.tx.Commit()
+	return session.executor.Commit(true)
 }
 
 func (session *DefaultSqlSession) Rollback() error {
 	session.logLastSql("Rollback", "")
-	return session.tx.Rollback()
+	return session.executor.Rollback(true)
+}
+
+func (session *DefaultSqlSession) Savepoint(name string) error {
+	session.logLastSql("Savepoint", name)
+	if err := executor.BeforeSavepoint(session.executor); err != nil {
+		return err
+	}
+	return session.tx.Savepoint(name)
+}
+
+func (session *DefaultSqlSession) RollbackTo(name string) error {
+	session.logLastSql("RollbackTo", name)
+	executor.BeforeRollbackTo(session.executor)
+	executor.ClearLocalCache(session.executor)
+	return session.tx.RollbackTo(name)
+}
+
+func (session *DefaultSqlSession) Release(name string) error {
+	session.logLastSql("Release", name)
+	return session.tx.Release(name)
+}
+
+func (session *DefaultSqlSession) ClearLocalCache() {
+	executor.ClearLocalCache(session.executor)
 }
 
 func (session *DefaultSqlSession) logLastSql(sql string, params ...interface{}) {
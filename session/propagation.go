@@ -17,122 +17,39 @@
 
 package session
 
-//
-//import "github.com/acmestack/gobatis/errors"
-//
-//type Propagation interface {
-//    Begin() error
-//    Commit() error
-//    Rollback() error
-//}
-//
-//type PropagationBase struct {
-//    sess SqlSession
-//}
-//
-//func (p *PropagationBase) SetSession(sess SqlSession) {
-//    p.sess = sess
-//}
-//
-////当前没有事务则新建事务，有则加入当前事务
-//type PropagationRequired struct {
-//    PropagationBase
-//
-//    beginCount int32
-//}
-//
-//func (p *PropagationRequired) Begin() error {
-//    if p.beginCount > 0 {
-//        return nil
-//    }
-//
-//    err := p.sess.Begin()
-//    if err != nil {
-//        return err
-//    }
-//
-//    p.beginCount++
-//    return nil
-//}
-//
-//func (p *PropagationRequired) Commit() error {
-//    if p.beginCount == 0 {
-//        return errors.TRANSACTION_WITHOUT_BEGIN
-//    }
-//
-//    p.beginCount--
-//    if p.beginCount == 0 {
-//        return p.sess.Commit()
-//    }
-//    return nil
-//}
-//
-//func (p *PropagationRequired) Rollback() error {
-//    if p.beginCount == 0 {
-//        return errors.TRANSACTION_WITHOUT_BEGIN
-//    }
-//
-//    p.beginCount--
-//    if p.beginCount == 0 {
-//        return p.sess.Rollback()
-//    }
-//    return nil
-//}
-//
-////支持当前事务，如果当前没有事务则以非事务方式执行
-//type PropagationSupports struct {
-//    PropagationBase
-//}
-//
-//func (p *PropagationSupports) Begin() error {
-//
-//}
-//
-//func (p *PropagationSupports) Commit() error {
-//    if p.beginCount == 0 {
-//        return errors.TRANSACTION_WITHOUT_BEGIN
-//    }
-//
-//    p.beginCount--
-//    if p.beginCount == 0 {
-//        return p.sess.Commit()
-//    }
-//    return nil
-//}
-//
-//func (p *PropagationSupports) Rollback() error {
-//    if p.beginCount == 0 {
-//        return errors.TRANSACTION_WITHOUT_BEGIN
-//    }
-//
-//    p.beginCount--
-//    if p.beginCount == 0 {
-//        return p.sess.Rollback()
-//    }
-//    return nil
-//}
-//
-////使用当前事务，如果没有则panic
-//type PropagationMandatory struct {
-//    PropagationBase
-//}
-//
-////新建事务，如果当前有事务则把当前事务挂起
-//type PropagationRequiredNew struct {
-//    PropagationBase
-//}
-//
-////以非事务方式执行操作，如果当前存在事务，就把当前事务挂起
-//type PropagationNotSupported struct {
-//    PropagationBase
-//}
-//
-////以非事务的方式执行，如果当前有事务则panic
-//type PropagationNever struct {
-//    PropagationBase
-//}
-//
-////如果当前存在事务，则在嵌套事务内执行。如果当前没有事务，则执行与PROPAGATION_REQUIRED类似的操作
-//type PropagationNested struct {
-//    PropagationBase
-//}
+// Propagation 事务传播行为，决定在已有事务中再次调用Tx时如何处理当前事务
+type Propagation int
+
+const (
+	// PropagationRequired 当前没有事务则新建事务，有则加入当前事务（默认）
+	PropagationRequired Propagation = iota
+	// PropagationSupports 支持当前事务，如果当前没有事务则以非事务方式执行
+	PropagationSupports
+	// PropagationMandatory 使用当前事务，如果当前没有事务则返回错误
+	PropagationMandatory
+	// PropagationRequiresNew 新建事务，如果当前有事务则把当前事务挂起
+	PropagationRequiresNew
+	// PropagationNotSupported 以非事务方式执行操作，如果当前存在事务，就把当前事务挂起
+	PropagationNotSupported
+	// PropagationNever 以非事务的方式执行，如果当前有事务则返回错误
+	PropagationNever
+	// PropagationNested 如果当前存在事务，则在嵌套事务内执行。如果当前没有事务，则执行与PropagationRequired类似的操作
+	PropagationNested
+)
+
+var gPropagationName = map[Propagation]string{
+	PropagationRequired:     "REQUIRED",
+	PropagationSupports:     "SUPPORTS",
+	PropagationMandatory:    "MANDATORY",
+	PropagationRequiresNew:  "REQUIRES_NEW",
+	PropagationNotSupported: "NOT_SUPPORTED",
+	PropagationNever:        "NEVER",
+	PropagationNested:       "NESTED",
+}
+
+func (p Propagation) String() string {
+	if name, ok := gPropagationName[p]; ok {
+		return name
+	}
+	return "UNKNOWN"
+}
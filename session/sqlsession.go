@@ -19,6 +19,9 @@ package session
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/acmestack/gobatis/executor"
 	"github.com/acmestack/gobatis/reflection"
 )
 
@@ -33,9 +36,20 @@ type SqlSession interface {
 
 	Delete(ctx context.Context, sql string, params ...interface{}) (int64, error)
 
-	Begin() error
+	FlushStatements(ctx context.Context) ([]executor.BatchResult, error)
+
+	Begin(ctx context.Context, opts *sql.TxOptions) error
 
 	Commit() error
 
 	Rollback() error
+
+	Savepoint(name string) error
+
+	RollbackTo(name string) error
+
+	Release(name string) error
+
+	// ClearLocalCache 清空一级缓存
+	ClearLocalCache()
 }
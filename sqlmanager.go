@@ -18,55 +18,54 @@
 package gobatis
 
 import (
-	"os"
+	"io/fs"
 	"path/filepath"
 
 	"github.com/acmestack/gobatis/parsing"
 	"github.com/acmestack/gobatis/parsing/sqlparser"
 	"github.com/acmestack/gobatis/parsing/template"
-	"github.com/acmestack/gobatis/parsing/xml"
 )
 
-type sqlManager struct {
-	dynamicSqlMgr  *xml.Manager
-	templateSqlMgr *template.Manager
-}
-
-var sqlMgr = sqlManager{
-	dynamicSqlMgr:  xml.NewManager(),
-	templateSqlMgr: template.NewManager(),
-}
-
 func RegisterSql(sqlId string, sql string) error {
-	return sqlMgr.dynamicSqlMgr.RegisterSql(sqlId, sql)
+	return defaultConfiguration.RegisterSql(sqlId, sql)
 }
 
 func UnregisterSql(sqlId string) {
-	sqlMgr.dynamicSqlMgr.UnregisterSql(sqlId)
+	defaultConfiguration.UnregisterSql(sqlId)
 }
 
 func RegisterMapperData(data []byte) error {
-	return sqlMgr.dynamicSqlMgr.RegisterData(data)
+	return defaultConfiguration.RegisterMapperData(data)
 }
 
 func RegisterMapperFile(file string) error {
-	return sqlMgr.dynamicSqlMgr.RegisterFile(file)
+	return defaultConfiguration.RegisterMapperFile(file)
+}
+
+// RegisterMapperFileFS 注册fsys中的mapper文件，可以用于embed.FS
+func RegisterMapperFileFS(fsys fs.FS, file string) error {
+	return defaultConfiguration.RegisterMapperFileFS(fsys, file)
 }
 
 func FindDynamicSqlParser(sqlId string) (sqlparser.SqlParser, bool) {
-	return sqlMgr.dynamicSqlMgr.FindSqlParser(sqlId)
+	return defaultConfiguration.FindDynamicSqlParser(sqlId)
 }
 
 func RegisterTemplateData(data []byte) error {
-	return sqlMgr.templateSqlMgr.RegisterData(data)
+	return defaultConfiguration.RegisterTemplateData(data)
 }
 
 func RegisterTemplateFile(file string) error {
-	return sqlMgr.templateSqlMgr.RegisterFile(file)
+	return defaultConfiguration.RegisterTemplateFile(file)
+}
+
+// RegisterTemplateFileFS 注册fsys中的模板文件，可以用于embed.FS
+func RegisterTemplateFileFS(fsys fs.FS, file string) error {
+	return defaultConfiguration.RegisterTemplateFileFS(fsys, file)
 }
 
 func FindTemplateSqlParser(sqlId string) (sqlparser.SqlParser, bool) {
-	return sqlMgr.templateSqlMgr.FindSqlParser(sqlId)
+	return defaultConfiguration.FindTemplateSqlParser(sqlId)
 }
 
 type ParserFactory func(sql string) (sqlparser.SqlParser, error)
@@ -80,28 +79,25 @@ func TemplateParserFactory(sql string) (sqlparser.SqlParser, error) {
 }
 
 func ScanMapperFile(dir string) error {
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	return defaultConfiguration.ScanMapperFile(dir)
+}
+
+// ScanMapperFS 扫描fsys中root目录下的xml及tpl文件并注册，可以用于embed.FS
+func ScanMapperFS(fsys fs.FS, root string) error {
+	return defaultConfiguration.ScanMapperFS(fsys, root)
+}
+
+// registerMapper 按扩展名注册xml或tpl文件，忽略其他文件
+func registerMapper(path string, xmlFunc, tplFunc func(file string) error) error {
+	filename := filepath.Base(path)
+	length := len(filename)
+	if length > 4 {
+		if filename[length-4:] == ".xml" {
+			return xmlFunc(path)
 		}
-		if !info.IsDir() {
-			filename := filepath.Base(path)
-			length := len(filename)
-			if length > 4 {
-				if filename[length-4:] == ".xml" {
-					err := RegisterMapperFile(path)
-					if err != nil {
-						return err
-					}
-				}
-				if filename[length-4:] == ".tpl" {
-					err := RegisterTemplateFile(path)
-					if err != nil {
-						return err
-					}
-				}
-			}
+		if filename[length-4:] == ".tpl" {
+			return tplFunc(path)
 		}
-		return nil
-	})
+	}
+	return nil
 }
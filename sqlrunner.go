@@ -20,10 +20,15 @@ package gobatis
 import (
 	"context"
 
+	"github.com/acmestack/gobatis/common"
+	"github.com/acmestack/gobatis/datasource"
 	"github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/executor"
 	"github.com/acmestack/gobatis/factory"
 	"github.com/acmestack/gobatis/logging"
+	"github.com/acmestack/gobatis/parsing"
 	"github.com/acmestack/gobatis/parsing/sqlparser"
+	"github.com/acmestack/gobatis/plugin"
 	"github.com/acmestack/gobatis/reflection"
 	"github.com/acmestack/gobatis/session"
 )
@@ -31,13 +36,22 @@ import (
 type SessionManager struct {
 	factory       factory.Factory
 	ParserFactory ParserFactory
+	interceptors  *plugin.InterceptorChain
+	config        *Configuration
 }
 
-func NewSessionManager(factory factory.Factory) *SessionManager {
-	return &SessionManager{
+// NewSessionManager 创建SessionManager，config为查找语句及解析模型使用的Configuration，不传入时使用默认的Configuration
+func NewSessionManager(factory factory.Factory, config ...*Configuration) *SessionManager {
+	ret := &SessionManager{
 		factory:       factory,
 		ParserFactory: DynamicParserFactory,
+		interceptors:  plugin.NewInterceptorChain(),
+		config:        defaultConfiguration,
 	}
+	if len(config) > 0 && config[0] != nil {
+		ret.config = config[0]
+	}
+	return ret
 }
 
 type Runner interface {
@@ -48,6 +62,9 @@ type Runner interface {
 	Param(params ...interface{}) Runner
 	// Result 获得结果
 	Result(bean interface{}) error
+	// Iterate 流式获得结果，每读取一行即反序列化到bean中并调用iterFunc，iterFunc返回true时停止迭代
+	// bean必须为指针，类型与Result中slice的元素类型一致；语句配置了resultMap时返回错误
+	Iterate(bean interface{}, iterFunc common.IterFunc) error
 	// LastInsertId 最后插入的自增id
 	LastInsertId() int64
 	// Context 设置Context
@@ -60,17 +77,28 @@ type Session struct {
 	session       session.SqlSession
 	driver        string
 	ParserFactory ParserFactory
+
+	factory      factory.Factory
+	interceptors *plugin.InterceptorChain
+	tx           *txStatus
+	config       *Configuration
 }
 
 type BaseRunner struct {
-	session   session.SqlSession
-	sqlParser sqlparser.SqlParser
-	action    string
-	metadata  *sqlparser.Metadata
-	log       logging.LogFunc
-	driver    string
-	ctx       context.Context
-	runner    Runner
+	session      session.SqlSession
+	sqlParser    sqlparser.SqlParser
+	sqlId        string
+	action       string
+	metadata     *sqlparser.Metadata
+	params       []interface{}
+	log          logging.LogFunc
+	driver       string
+	dataSource   datasource.DataSource
+	ctx          context.Context
+	interceptors *plugin.InterceptorChain
+	tx           *txStatus
+	config       *Configuration
+	runner       Runner
 }
 
 type SelectRunner struct {
@@ -96,25 +124,42 @@ type ExecRunner struct {
 
 // NewSession 使用一个session操作数据库
 func (sessionManager *SessionManager) NewSession() *Session {
-	return &Session{
-		ctx:           context.Background(),
-		log:           sessionManager.factory.LogFunc(),
-		session:       sessionManager.factory.CreateSession(),
-		driver:        sessionManager.factory.GetDataSource().DriverName(),
-		ParserFactory: sessionManager.ParserFactory,
-	}
+	return sessionManager.createSession(context.Background())
 }
 
 // Context 包含session的context
 func (sessionManager *SessionManager) Context(ctx context.Context) context.Context {
-	sess := &Session{
+	sess := sessionManager.createSession(ctx)
+	return context.WithValue(ctx, ContextSessionKey, sess)
+}
+
+// AddInterceptor 添加拦截器，只对之后创建的Session生效，可以与NewSession并发调用
+func (sessionManager *SessionManager) AddInterceptor(interceptors ...plugin.Interceptor) {
+	sessionManager.interceptors.Add(interceptors...)
+}
+
+func (sessionManager *SessionManager) createSession(ctx context.Context) *Session {
+	interceptors := plugin.NewInterceptorChain(sessionManager.interceptors.Interceptors()...)
+	return &Session{
 		ctx:           ctx,
 		log:           sessionManager.factory.LogFunc(),
-		session:       sessionManager.factory.CreateSession(),
+		session:       createSqlSession(sessionManager.factory, interceptors),
 		driver:        sessionManager.factory.GetDataSource().DriverName(),
 		ParserFactory: sessionManager.ParserFactory,
+		factory:       sessionManager.factory,
+		interceptors:  interceptors,
+		config:        sessionManager.config,
 	}
-	return context.WithValue(ctx, ContextSessionKey, sess)
+}
+
+// createSqlSession 存在拦截器时使用拦截器包装执行器
+func createSqlSession(fac factory.Factory, interceptors *plugin.InterceptorChain) session.SqlSession {
+	if interceptors.Len() == 0 {
+		return fac.CreateSession()
+	}
+	tx := fac.CreateTransaction()
+	e := interceptors.WrapExecutor(fac.CreateExecutor(tx))
+	return session.NewDefaultSqlSession(fac.LogFunc(), tx, e, false)
 }
 
 func WithSession(ctx context.Context, sess *Session) context.Context {
@@ -128,6 +173,11 @@ func FindSession(ctx context.Context) *Session {
 	return ctx.Value(ContextSessionKey).(*Session)
 }
 
+// Configuration 获得SessionManager使用的Configuration
+func (sessionManager *SessionManager) Configuration() *Configuration {
+	return sessionManager.config
+}
+
 func (sessionManager *SessionManager) Close() error {
 	return sessionManager.factory.Close()
 }
@@ -151,50 +201,42 @@ func (session *Session) SetParserFactory(fac ParserFactory) {
 	session.ParserFactory = fac
 }
 
-// Tx 开启事务执行语句
+// Tx 开启事务执行语句，传播行为为PropagationRequired
 // 返回nil则提交，返回error回滚
 // 抛出异常错误触发回滚
-func (session *Session) Tx(txFunc func(session *Session) error) (err error) {
-	e1 := session.session.Begin()
-	if e1 != nil {
-		return e1
-	}
-	defer func(err *error) {
-		if r := recover(); r != nil {
-			*err = session.session.Rollback()
-			panic(r)
-		}
-	}(&err)
+func (session *Session) Tx(txFunc func(session *Session) error) error {
+	return session.TxWithPropagation(PropagationRequired, txFunc)
+}
 
-	if fnErr := txFunc(session); fnErr != nil {
-		e := session.session.Rollback()
-		if e != nil {
-			session.log(logging.WARN, "Rollback error: %v , business error: %v\n", e, fnErr)
-		}
-		return fnErr
-	} else {
-		return session.session.Commit()
-	}
+// FlushStatements 执行批量执行器中排队的语句，返回每个语句影响的行数
+// 仅在执行器类型为executor.TypeBatch时有效，其他执行器返回nil
+func (session *Session) FlushStatements() ([]executor.BatchResult, error) {
+	return session.session.FlushStatements(session.ctx)
+}
+
+// ClearLocalCache 清空session的一级缓存，未开启一级缓存时不做任何处理
+func (session *Session) ClearLocalCache() {
+	session.session.ClearLocalCache()
 }
 
 func (session *Session) Select(sql string) Runner {
-	return session.createSelect(session.findSqlParser(sql))
+	return session.createSelect(sql, session.findSqlParser(sql))
 }
 
 func (session *Session) Update(sql string) Runner {
-	return session.createUpdate(session.findSqlParser(sql))
+	return session.createUpdate(sql, session.findSqlParser(sql))
 }
 
 func (session *Session) Delete(sql string) Runner {
-	return session.createDelete(session.findSqlParser(sql))
+	return session.createDelete(sql, session.findSqlParser(sql))
 }
 
 func (session *Session) Insert(sql string) Runner {
-	return session.createInsert(session.findSqlParser(sql))
+	return session.createInsert(sql, session.findSqlParser(sql))
 }
 
 func (session *Session) Exec(sql string) Runner {
-	return session.createExec(session.findSqlParser(sql))
+	return session.createExec(sql, session.findSqlParser(sql))
 }
 
 func (baseRunner *BaseRunner) Param(params ...interface{}) Runner {
@@ -215,6 +257,7 @@ func (baseRunner *BaseRunner) Param(params ...interface{}) Runner {
 		return baseRunner
 	}
 
+	baseRunner.params = params
 	md, err := baseRunner.sqlParser.ParseMetadata(baseRunner.driver, params...)
 
 	if err == nil {
@@ -237,35 +280,113 @@ func (baseRunner *BaseRunner) Context(ctx context.Context) Runner {
 	return baseRunner.runner
 }
 
+// prepare 获得执行使用的context及Metadata，存在拦截器时调用拦截器处理Metadata的副本
+func (baseRunner *BaseRunner) prepare() (context.Context, *sqlparser.Metadata, error) {
+	if baseRunner.metadata == nil {
+		baseRunner.log(logging.WARN, "Sql Metadata is nil")
+		return nil, nil, errors.RunnerNotReady
+	}
+
+	if baseRunner.interceptors.Len() == 0 {
+		return baseRunner.ctx, baseRunner.metadata, nil
+	}
+
+	ctx := plugin.WithSqlId(baseRunner.ctx, baseRunner.sqlId)
+	md := *baseRunner.metadata
+	md.Params = append([]interface{}(nil), md.Params...)
+	ret, err := baseRunner.interceptors.InterceptMetadata(ctx, baseRunner.sqlId, &md)
+	return ctx, ret, err
+}
+
+// complete 调用拦截器处理执行结果
+func (baseRunner *BaseRunner) complete(ctx context.Context, md *sqlparser.Metadata, bean interface{}, err error) error {
+	if baseRunner.interceptors.Len() == 0 {
+		return err
+	}
+	return baseRunner.interceptors.InterceptResult(ctx, baseRunner.sqlId, md, bean, err)
+}
+
 func (selectRunner *SelectRunner) Result(bean interface{}) error {
-	if selectRunner.metadata == nil {
-		selectRunner.log(logging.WARN, "Sql Metadata is nil")
-		return errors.RunnerNotReady
+	ctx, md, err := selectRunner.prepare()
+	if err != nil {
+		return err
 	}
+	ctx, cancel := selectRunner.statementContext(ctx)
+	defer cancel()
 
 	if reflection.IsNil(bean) {
 		return errors.ResultPointerIsNil
 	}
 
-	obj, err := ParseObject(bean)
+	obj, err := selectRunner.resultObject(bean)
 	if err != nil {
 		return err
 	}
-	return selectRunner.session.Query(selectRunner.ctx, obj, selectRunner.metadata.PrepareSql, selectRunner.metadata.Params...)
+	err = selectRunner.query(ctx, obj, md)
+	return selectRunner.complete(ctx, md, bean, err)
+}
+
+func (selectRunner *SelectRunner) Iterate(bean interface{}, iterFunc common.IterFunc) error {
+	ctx, md, err := selectRunner.prepare()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := selectRunner.statementContext(ctx)
+	defer cancel()
+
+	if reflection.IsNil(bean) {
+		return errors.ResultPointerIsNil
+	}
+
+	if iterFunc == nil {
+		return errors.IterFuncIsNil
+	}
 
+	//resultMap的嵌套映射需要合并多行数据，无法逐行迭代
+	if stmt := selectRunner.statement(); stmt != nil && stmt.ResultMap != nil {
+		return errors.IterateResultMapNotSupport
+	}
+	obj, err := selectRunner.config.ParseObject(bean)
+	if err != nil {
+		return err
+	}
+	if obj.CanAddValue() {
+		return errors.IterateSliceNotSupport
+	}
+	iterObj := reflection.NewIterObject(bean, obj, iterFunc)
+	err = selectRunner.query(ctx, iterObj, md)
+	return selectRunner.complete(ctx, md, bean, err)
 }
 
 func (insertRunner *InsertRunner) Result(bean interface{}) error {
-	if insertRunner.metadata == nil {
-		insertRunner.log(logging.WARN, "Sql Metadata is nil")
-		return errors.RunnerNotReady
+	if err := insertRunner.selectKeyBefore(); err != nil {
+		return err
+	}
+	ctx, md, err := insertRunner.prepare()
+	if err != nil {
+		return err
 	}
-	i, id, err := insertRunner.session.Insert(insertRunner.ctx, insertRunner.metadata.PrepareSql, insertRunner.metadata.Params...)
+	ctx, cancel := insertRunner.statementContext(ctx)
+	defer cancel()
+	var i, id int64
+	stmt := insertRunner.statement()
+	if insertRunner.useReturning(stmt) {
+		i, err = insertRunner.insertReturning(ctx, md, stmt)
+	} else {
+		i, id, err = insertRunner.session.Insert(ctx, md.PrepareSql, md.Params...)
+		if err == nil {
+			insertRunner.writeGeneratedIds(stmt, i, id)
+		}
+	}
+	if err == nil {
+		err = insertRunner.selectKey(ctx, parsing.SelectKeyAfter)
+	}
+	insertRunner.flushCache()
 	insertRunner.lastId = id
 	if reflection.CanSet(bean) {
 		reflection.SetValue(reflection.ReflectValue(bean), i)
 	}
-	return err
+	return insertRunner.complete(ctx, md, bean, err)
 }
 
 func (insertRunner *InsertRunner) LastInsertId() int64 {
@@ -273,39 +394,54 @@ func (insertRunner *InsertRunner) LastInsertId() int64 {
 }
 
 func (updateRunner *UpdateRunner) Result(bean interface{}) error {
-	if updateRunner.metadata == nil {
-		updateRunner.log(logging.WARN, "Sql Metadata is nil")
-		return errors.RunnerNotReady
+	if err := updateRunner.selectKeyBefore(); err != nil {
+		return err
 	}
-	i, err := updateRunner.session.Update(updateRunner.ctx, updateRunner.metadata.PrepareSql, updateRunner.metadata.Params...)
+	ctx, md, err := updateRunner.prepare()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := updateRunner.statementContext(ctx)
+	defer cancel()
+	i, err := updateRunner.session.Update(ctx, md.PrepareSql, md.Params...)
+	if err == nil {
+		err = updateRunner.selectKey(ctx, parsing.SelectKeyAfter)
+	}
+	updateRunner.flushCache()
 	if reflection.CanSet(bean) {
 		reflection.SetValue(reflection.ReflectValue(bean), i)
 	}
-	return err
+	return updateRunner.complete(ctx, md, bean, err)
 }
 
 func (execRunner *ExecRunner) Result(bean interface{}) error {
-	if execRunner.metadata == nil {
-		execRunner.log(logging.WARN, "Sql Metadata is nil")
-		return errors.RunnerNotReady
+	ctx, md, err := execRunner.prepare()
+	if err != nil {
+		return err
 	}
-	i, err := execRunner.session.Update(execRunner.ctx, execRunner.metadata.PrepareSql, execRunner.metadata.Params...)
+	ctx, cancel := execRunner.statementContext(ctx)
+	defer cancel()
+	i, err := execRunner.session.Update(ctx, md.PrepareSql, md.Params...)
+	execRunner.flushCache()
 	if reflection.CanSet(bean) {
 		reflection.SetValue(reflection.ReflectValue(bean), i)
 	}
-	return err
+	return execRunner.complete(ctx, md, bean, err)
 }
 
 func (deleteRunner *DeleteRunner) Result(bean interface{}) error {
-	if deleteRunner.metadata == nil {
-		deleteRunner.log(logging.WARN, "Sql Metadata is nil")
-		return errors.RunnerNotReady
+	ctx, md, err := deleteRunner.prepare()
+	if err != nil {
+		return err
 	}
-	i, err := deleteRunner.session.Delete(deleteRunner.ctx, deleteRunner.metadata.PrepareSql, deleteRunner.metadata.Params...)
+	ctx, cancel := deleteRunner.statementContext(ctx)
+	defer cancel()
+	i, err := deleteRunner.session.Delete(ctx, md.PrepareSql, md.Params...)
+	deleteRunner.flushCache()
 	if reflection.CanSet(bean) {
 		reflection.SetValue(reflection.ReflectValue(bean), i)
 	}
-	return err
+	return deleteRunner.complete(ctx, md, bean, err)
 }
 
 func (baseRunner *BaseRunner) Result(bean interface{}) error {
@@ -314,75 +450,101 @@ func (baseRunner *BaseRunner) Result(bean interface{}) error {
 	//return nil, nil
 }
 
+func (baseRunner *BaseRunner) Iterate(bean interface{}, iterFunc common.IterFunc) error {
+	return errors.RunnerIterateNotSupport
+}
+
 func (baseRunner *BaseRunner) LastInsertId() int64 {
 	return -1
 }
 
-func (session *Session) createSelect(parser sqlparser.SqlParser) Runner {
+func (session *Session) createSelect(sqlId string, parser sqlparser.SqlParser) Runner {
 	ret := &SelectRunner{}
 	ret.action = sqlparser.SELECT
 	ret.log = session.log
 	ret.session = session.session
 	ret.sqlParser = parser
+	ret.sqlId = sqlId
 	ret.ctx = session.ctx
+	ret.interceptors = session.interceptors
+	ret.tx = session.tx
 	ret.driver = session.driver
+	ret.dataSource = session.factory.GetDataSource()
+	ret.config = session.config
 	ret.runner = ret
 	return ret
 }
 
-func (session *Session) createUpdate(parser sqlparser.SqlParser) Runner {
+func (session *Session) createUpdate(sqlId string, parser sqlparser.SqlParser) Runner {
 	ret := &UpdateRunner{}
 	ret.action = sqlparser.UPDATE
 	ret.log = session.log
 	ret.session = session.session
 	ret.sqlParser = parser
+	ret.sqlId = sqlId
 	ret.ctx = session.ctx
+	ret.interceptors = session.interceptors
+	ret.tx = session.tx
 	ret.driver = session.driver
+	ret.dataSource = session.factory.GetDataSource()
+	ret.config = session.config
 	ret.runner = ret
 	return ret
 }
 
-func (session *Session) createDelete(parser sqlparser.SqlParser) Runner {
+func (session *Session) createDelete(sqlId string, parser sqlparser.SqlParser) Runner {
 	ret := &DeleteRunner{}
 	ret.action = sqlparser.DELETE
 	ret.log = session.log
 	ret.session = session.session
 	ret.sqlParser = parser
+	ret.sqlId = sqlId
 	ret.ctx = session.ctx
+	ret.interceptors = session.interceptors
+	ret.tx = session.tx
 	ret.driver = session.driver
+	ret.dataSource = session.factory.GetDataSource()
+	ret.config = session.config
 	ret.runner = ret
 	return ret
 }
 
-func (session *Session) createInsert(parser sqlparser.SqlParser) Runner {
+func (session *Session) createInsert(sqlId string, parser sqlparser.SqlParser) Runner {
 	ret := &InsertRunner{}
 	ret.action = sqlparser.INSERT
 	ret.log = session.log
 	ret.session = session.session
 	ret.sqlParser = parser
+	ret.sqlId = sqlId
 	ret.ctx = session.ctx
+	ret.interceptors = session.interceptors
+	ret.tx = session.tx
 	ret.driver = session.driver
+	ret.dataSource = session.factory.GetDataSource()
+	ret.config = session.config
 	ret.runner = ret
 	return ret
 }
 
-func (session *Session) createExec(parser sqlparser.SqlParser) Runner {
+func (session *Session) createExec(sqlId string, parser sqlparser.SqlParser) Runner {
 	ret := &ExecRunner{}
 	ret.action = ""
 	ret.log = session.log
 	ret.session = session.session
 	ret.sqlParser = parser
+	ret.sqlId = sqlId
 	ret.ctx = session.ctx
+	ret.interceptors = session.interceptors
+	ret.tx = session.tx
 	ret.driver = session.driver
+	ret.dataSource = session.factory.GetDataSource()
+	ret.config = session.config
 	ret.runner = ret
 	return ret
 }
 
 func (session *Session) findSqlParser(sqlId string) sqlparser.SqlParser {
-	ret, ok := FindDynamicSqlParser(sqlId)
-	if !ok {
-		ret, ok = FindTemplateSqlParser(sqlId)
-	}
+	ret, ok := session.config.FindSqlParser(sqlId)
 	//FIXME: 当没有查找到sqlId对应的sql语句，则尝试使用sqlId直接操作数据库
 	//该设计可能需要设计一个更合理的方式
 	if !ok {
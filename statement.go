@@ -0,0 +1,57 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gobatis
+
+import (
+	"context"
+
+	"github.com/acmestack/gobatis/executor"
+	"github.com/acmestack/gobatis/parsing"
+	"github.com/acmestack/gobatis/reflection"
+)
+
+// statement 获得mapper文件中语句的配置，直接使用sql语句时返回nil
+func (baseRunner *BaseRunner) statement() *parsing.Statement {
+	if d, ok := baseRunner.sqlParser.(*parsing.DynamicData); ok {
+		return d.Statement
+	}
+	return nil
+}
+
+// resultObject 获得反序列化结果使用的Object，语句配置了resultMap时按resultMap映射
+func (baseRunner *BaseRunner) resultObject(bean interface{}) (reflection.Object, error) {
+	if stmt := baseRunner.statement(); stmt != nil && stmt.ResultMap != nil {
+		return reflection.NewResultMapObject(stmt.ResultMap, bean)
+	}
+	return baseRunner.config.ParseObject(bean)
+}
+
+// statementContext 使用语句配置的timeout及fetchSize生成执行的context，执行结束后需调用返回的CancelFunc
+func (baseRunner *BaseRunner) statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	stmt := baseRunner.statement()
+	if stmt == nil {
+		return ctx, func() {}
+	}
+	if stmt.FetchSize > 0 {
+		ctx = executor.WithFetchSize(ctx, stmt.FetchSize)
+	}
+	if stmt.Timeout > 0 {
+		return context.WithTimeout(ctx, stmt.Timeout)
+	}
+	return ctx, func() {}
+}
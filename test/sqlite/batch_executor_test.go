@@ -0,0 +1,192 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/acmestack/gobatis"
+	"github.com/acmestack/gobatis/datasource"
+	"github.com/acmestack/gobatis/executor"
+	"testing"
+)
+
+func TestBatchExecutor(t *testing.T) {
+	initTest(t)
+	fac := gobatis.NewFactory(
+		gobatis.SetMaxConn(100),
+		gobatis.SetMaxIdleConn(50),
+		gobatis.SetExecutorType(executor.TypeBatch),
+		gobatis.SetBatchSize(3),
+		gobatis.SetDataSource(&datasource.SqliteDataSource{
+			Path: "test.db",
+		}))
+	defer fac.Close()
+	mgr := gobatis.NewSessionManager(fac)
+	insertSql := "INSERT INTO test_table(id, username, password) VALUES(#{0}, #{1}, #{2})"
+	updateSql := "UPDATE test_table SET password = #{0} WHERE id = #{1}"
+
+	t.Run("without tx", func(t *testing.T) {
+		count := 0
+		err := mgr.NewSession().Insert(insertSql).Param(100, "user", "pw").Result(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatal("expect executed immediately")
+		}
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		initTest(t)
+		err := mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			for i := 1; i <= 5; i++ {
+				err := sess.Insert(insertSql).Param(i, fmt.Sprintf("user%d", i), "pw").Result(nil)
+				if err != nil {
+					return err
+				}
+			}
+			err := sess.Update(updateSql).Param("new_pw", 1).Result(nil)
+			if err != nil {
+				return err
+			}
+			err = sess.Update(updateSql).Param("new_pw", 100).Result(nil)
+			if err != nil {
+				return err
+			}
+
+			results, err := sess.FlushStatements()
+			if err != nil {
+				return err
+			}
+			//batch size is 3
+			if len(results) != 3 {
+				t.Fatalf("expect 3 batch, get %d", len(results))
+			}
+			if len(results[0].UpdateCounts) != 3 || len(results[1].UpdateCounts) != 2 {
+				t.Fatalf("expect insert batch 3 and 2, get %v", results)
+			}
+			if results[2].Sql != "UPDATE test_table SET password = ? WHERE id = ?" ||
+				results[2].UpdateCounts[0] != 1 || results[2].UpdateCounts[1] != 0 {
+				t.Fatalf("expect update counts [1 0], get %v", results[2])
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if countTestTable(t, mgr) != 5 {
+			t.Fatal("expect 5 rows")
+		}
+	})
+
+	t.Run("query and commit", func(t *testing.T) {
+		initTest(t)
+		err := mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			err := sess.Insert(insertSql).Param(1, "user1", "pw").Result(nil)
+			if err != nil {
+				return err
+			}
+			count := 0
+			err = sess.Select("SELECT count(*) FROM test_table").Param().Result(&count)
+			if err != nil {
+				return err
+			}
+			if count != 1 {
+				t.Fatal("expect flush before query")
+			}
+			return sess.Insert(insertSql).Param(2, "user2", "pw").Result(nil)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if countTestTable(t, mgr) != 2 {
+			t.Fatal("expect flush when commit")
+		}
+	})
+
+	t.Run("results after commit", func(t *testing.T) {
+		initTest(t)
+		sess := mgr.NewSession()
+		err := sess.Tx(func(sess *gobatis.Session) error {
+			for i := 1; i <= 2; i++ {
+				err := sess.Insert(insertSql).Param(i, fmt.Sprintf("user%d", i), "pw").Result(nil)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := sess.FlushStatements()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || len(results[0].UpdateCounts) != 2 {
+			t.Fatalf("expect results of commit, get %v", results)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		initTest(t)
+		err := mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			sess.Insert(insertSql).Param(1, "user1", "pw").Result(nil)
+			//duplicate primary key
+			sess.Insert(insertSql).Param(1, "user1", "pw").Result(nil)
+			return nil
+		})
+		if err == nil {
+			t.Fatal("expect error")
+		}
+		t.Log(err)
+		if countTestTable(t, mgr) != 0 {
+			t.Fatal("expect rollback")
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		initTest(t)
+		err := mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			//queued before savepoint, must be executed before it
+			if err := sess.Insert(insertSql).Param(1, "outer", "pw").Result(nil); err != nil {
+				return err
+			}
+			nestedErr := sess.Nested(func(sess *gobatis.Session) error {
+				for i := 2; i <= 3; i++ {
+					err := sess.Insert(insertSql).Param(i, fmt.Sprintf("user%d", i), "pw").Result(nil)
+					if err != nil {
+						return err
+					}
+				}
+				return errors.New("nested failed")
+			})
+			if nestedErr == nil {
+				t.Fatal("expect nested error")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if countTestTable(t, mgr) != 1 {
+			t.Fatal("expect only outer row after commit")
+		}
+	})
+}
@@ -0,0 +1,64 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"fmt"
+	"github.com/acmestack/gobatis"
+	"testing"
+)
+
+func TestConfiguration(t *testing.T) {
+	initTest(t)
+	mapper := `<mapper namespace="configTest">
+    <select id="selectName">SELECT %s FROM test_table WHERE id = #{0}</select>
+</mapper>`
+	config1 := gobatis.NewConfiguration()
+	if err := config1.RegisterMapperData([]byte(fmt.Sprintf(mapper, "username"))); err != nil {
+		t.Fatal(err)
+	}
+	config2 := gobatis.NewConfiguration()
+	if err := config2.RegisterMapperData([]byte(fmt.Sprintf(mapper, "password"))); err != nil {
+		t.Fatal(err)
+	}
+	fac := connect()
+	defer fac.Close()
+	mgr1 := gobatis.NewSessionManager(fac, config1)
+	mgr2 := gobatis.NewSessionManager(fac, config2)
+
+	err := mgr1.NewSession().Insert("INSERT INTO test_table(id, username, password) VALUES(1, 'user', 'pw')").Param().Result(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name1, name2 string
+	if err := mgr1.NewSession().Select("configTest.selectName").Param(1).Result(&name1); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr2.NewSession().Select("configTest.selectName").Param(1).Result(&name2); err != nil {
+		t.Fatal(err)
+	}
+	if name1 != "user" || name2 != "pw" {
+		t.Fatalf("expect different statements, get %s %s", name1, name2)
+	}
+	if _, ok := gobatis.FindDynamicSqlParser("configTest.selectName"); ok {
+		t.Fatal("expect default configuration not changed")
+	}
+	if gobatis.NewSessionManager(fac).Configuration() != gobatis.DefaultConfiguration() {
+		t.Fatal("expect default configuration")
+	}
+}
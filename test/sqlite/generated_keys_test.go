@@ -0,0 +1,206 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"fmt"
+	"github.com/acmestack/gobatis"
+	"github.com/acmestack/gobatis/datasource"
+	"github.com/acmestack/gobatis/executor"
+	"github.com/acmestack/gobatis/logging"
+	"strings"
+	"testing"
+)
+
+var generatedKeysMapper = `<?xml version="1.0" encoding="UTF-8"?>
+<mapper namespace="generatedKeysTest">
+    <insert id="insertUser" useGeneratedKeys="true" keyProperty="id">
+        INSERT INTO test_table(username, password) VALUES(#{TestTable.username}, #{TestTable.password})
+    </insert>
+    <insert id="insertUsers" useGeneratedKeys="true" keyProperty="Id">
+        INSERT INTO test_table(username, password) VALUES
+        <foreach item="u" collection="{0}" separator=",">(#{u.TestTable.username}, #{u.TestTable.password})</foreach>
+    </insert>
+    <insert id="insertMultiKeys" useGeneratedKeys="true" keyProperty="id,createTime">
+        INSERT INTO test_table(username, password) VALUES(#{TestTable.username}, #{TestTable.password})
+    </insert>
+    <insert id="insertMap" useGeneratedKeys="true" keyProperty="id">
+        INSERT INTO test_table(username, password) VALUES(#{username}, #{password})
+    </insert>
+    <insert id="insertTrailing" useGeneratedKeys="true" keyProperty="id">
+        INSERT INTO test_table(username, password) VALUES(#{TestTable.username}, '--;') ; -- trailing comment
+        /* block comment */
+    </insert>
+    <insert id="insertReturning" useGeneratedKeys="true" keyProperty="id">
+        INSERT INTO test_table(username, password) VALUES(#{TestTable.username}, #{TestTable.password}) RETURNING id;
+    </insert>
+    <insert id="insertBefore">
+        <selectKey keyProperty="id" order="BEFORE">SELECT ifnull(max(id), 0) + 100 AS id FROM test_table</selectKey>
+        INSERT INTO test_table(id, username, password) VALUES(#{TestTable.id}, #{TestTable.username}, #{TestTable.password})
+    </insert>
+    <insert id="insertAfter">
+        <selectKey keyProperty="TestTable.id" keyColumn="last" order="AFTER">SELECT max(id) AS last FROM test_table</selectKey>
+        INSERT INTO test_table(username, password) VALUES(#{TestTable.username}, #{TestTable.password})
+    </insert>
+</mapper>`
+
+func TestGeneratedKeys(t *testing.T) {
+	initTest(t)
+	if err := gobatis.RegisterMapperData([]byte(generatedKeysMapper)); err != nil {
+		t.Fatal(err)
+	}
+	fac := connect()
+	defer fac.Close()
+	mgr := gobatis.NewSessionManager(fac)
+
+	findId := func(username string) int64 {
+		var v TestTable
+		err := mgr.NewSession().Select("SELECT * FROM test_table WHERE username = #{0}").Param(username).Result(&v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v.Id
+	}
+
+	t.Run("single", func(t *testing.T) {
+		v := TestTable{Username: "key_single", Password: "pw"}
+		err := mgr.NewSession().Insert("generatedKeysTest.insertUser").Param(&v).Result(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Id == 0 || v.Id != findId("key_single") {
+			t.Fatalf("expect generated id, get %v", v)
+		}
+	})
+
+	t.Run("multi rows", func(t *testing.T) {
+		users := []TestTable{{Username: "key_multi1", Password: "pw"}, {Username: "key_multi2", Password: "pw"}}
+		count := 0
+		err := mgr.NewSession().Insert("generatedKeysTest.insertUsers").Param(users).Result(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 || users[0].Id != findId("key_multi1") || users[1].Id != findId("key_multi2") {
+			t.Fatalf("expect generated ids, get %v", users)
+		}
+	})
+
+	t.Run("map", func(t *testing.T) {
+		m := map[string]interface{}{"username": "key_map", "password": "pw"}
+		err := mgr.NewSession().Insert("generatedKeysTest.insertMap").Param(m).Result(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m["id"] != findId("key_map") {
+			t.Fatalf("expect generated id, get %v", m)
+		}
+	})
+
+	t.Run("selectKey before", func(t *testing.T) {
+		v := TestTable{Username: "key_before", Password: "pw"}
+		err := mgr.NewSession().Insert("generatedKeysTest.insertBefore").Param(&v).Result(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Id < 100 || v.Id != findId("key_before") {
+			t.Fatalf("expect selected id, get %v", v)
+		}
+	})
+
+	t.Run("selectKey after", func(t *testing.T) {
+		v := TestTable{Username: "key_after", Password: "pw"}
+		err := mgr.NewSession().Insert("generatedKeysTest.insertAfter").Param(&v).Result(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Id == 0 || v.Id != findId("key_after") {
+			t.Fatalf("expect selected id, get %v", v)
+		}
+	})
+
+	var warns []string
+	logFunc := func(level int, format string, args ...interface{}) {
+		if level == logging.WARN {
+			warns = append(warns, fmt.Sprintf(format, args...))
+		}
+	}
+
+	t.Run("multi key properties", func(t *testing.T) {
+		warns = nil
+		warnFac := gobatis.NewFactory(gobatis.SetLog(logFunc), gobatis.SetDataSource(&datasource.SqliteDataSource{
+			Path: "test.db",
+		}))
+		defer warnFac.Close()
+		v := TestTable{Username: "key_multi_props", Password: "pw"}
+		err := gobatis.NewSessionManager(warnFac).NewSession().Insert("generatedKeysTest.insertMultiKeys").Param(&v).Result(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Id != findId("key_multi_props") {
+			t.Fatalf("expect first key written, get %v", v)
+		}
+		if len(warns) != 1 || !strings.Contains(warns[0], "createTime") {
+			t.Fatalf("expect warning of skipped key, get %v", warns)
+		}
+	})
+
+	t.Run("batch executor", func(t *testing.T) {
+		warns = nil
+		batchFac := gobatis.NewFactory(gobatis.SetLog(logFunc), gobatis.SetExecutorType(executor.TypeBatch),
+			gobatis.SetDataSource(&datasource.SqliteDataSource{
+				Path: "test.db",
+			}))
+		defer batchFac.Close()
+		v := TestTable{Username: "key_batch", Password: "pw"}
+		err := gobatis.NewSessionManager(batchFac).NewSession().Tx(func(sess *gobatis.Session) error {
+			return sess.Insert("generatedKeysTest.insertUser").Param(&v).Result(nil)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Id != 0 || findId("key_batch") == 0 {
+			t.Fatalf("expect queued insert not write key, get %v", v)
+		}
+		if len(warns) != 1 || !strings.Contains(warns[0], "batch") {
+			t.Fatalf("expect warning of batch executor, get %v", warns)
+		}
+	})
+
+	t.Run("returning", func(t *testing.T) {
+		gobatis.RegisterGeneratedKeysMode("sqlite3", gobatis.GeneratedKeysReturning)
+		defer gobatis.RegisterGeneratedKeysMode("sqlite3", gobatis.GeneratedKeysLastId)
+
+		v := TestTable{Username: "key_trailing", Password: "pw"}
+		err := mgr.NewSession().Insert("generatedKeysTest.insertTrailing").Param(&v).Result(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Id == 0 || v.Id != findId("key_trailing") {
+			t.Fatalf("expect returning appended before trailing semicolon and comments, get %v", v)
+		}
+
+		v = TestTable{Username: "key_returning", Password: "pw"}
+		err = mgr.NewSession().Insert("generatedKeysTest.insertReturning").Param(&v).Result(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Id == 0 || v.Id != findId("key_returning") {
+			t.Fatalf("expect existing returning clause used, get %v", v)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/acmestack/gobatis"
+	"github.com/acmestack/gobatis/executor"
+	"github.com/acmestack/gobatis/parsing/sqlparser"
+	"github.com/acmestack/gobatis/plugin"
+	"github.com/acmestack/gobatis/reflection"
+	"testing"
+)
+
+type tenantInterceptor struct {
+	plugin.BaseInterceptor
+	sqlIds []string
+}
+
+func (i *tenantInterceptor) InterceptMetadata(ctx context.Context, sqlId string, md *sqlparser.Metadata) (*sqlparser.Metadata, error) {
+	i.sqlIds = append(i.sqlIds, sqlId)
+	if md.Action == "select" {
+		md.PrepareSql += " AND username = ?"
+		md.Params = append(md.Params, "tenant")
+	}
+	return md, nil
+}
+
+func (i *tenantInterceptor) InterceptResult(ctx context.Context, sqlId string, md *sqlparser.Metadata, bean interface{}, err error) error {
+	if v, ok := bean.(*[]TestTable); ok && err == nil {
+		for j := range *v {
+			(*v)[j].Password = "***"
+		}
+	}
+	return err
+}
+
+type countExecutor struct {
+	executor.Executor
+	sqlIds []string
+}
+
+func (e *countExecutor) Query(ctx context.Context, result reflection.Object, sql string, params ...interface{}) error {
+	e.sqlIds = append(e.sqlIds, plugin.SqlId(ctx))
+	return e.Executor.Query(ctx, result, sql, params...)
+}
+
+type wrapInterceptor struct {
+	plugin.BaseInterceptor
+	e *countExecutor
+}
+
+func (i *wrapInterceptor) WrapExecutor(e executor.Executor) executor.Executor {
+	i.e = &countExecutor{Executor: e}
+	return i.e
+}
+
+func TestInterceptor(t *testing.T) {
+	initTest(t)
+	fac := connect()
+	defer fac.Close()
+	mgr := gobatis.NewSessionManager(fac)
+	tenant := &tenantInterceptor{}
+	wrap := &wrapInterceptor{}
+	mgr.AddInterceptor(tenant, wrap)
+
+	sess := mgr.NewSession()
+	insertSql := "INSERT INTO test_table(username, password) VALUES(#{0}, #{1})"
+	for _, name := range []string{"tenant", "other"} {
+		if err := sess.Insert(insertSql).Param(name, "pw").Result(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	selectSql := "SELECT * FROM test_table WHERE 1 = 1"
+	var ret []TestTable
+	err := sess.Select(selectSql).Param().Result(&ret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret) != 1 || ret[0].Username != "tenant" || ret[0].Password != "***" {
+		t.Fatalf("expect rewritten sql and masked result, get %v", ret)
+	}
+	if len(tenant.sqlIds) != 3 || tenant.sqlIds[2] != selectSql {
+		t.Fatalf("expect sql ids, get %v", tenant.sqlIds)
+	}
+	if wrap.e == nil || len(wrap.e.sqlIds) != 1 || wrap.e.sqlIds[0] != selectSql {
+		t.Fatal("expect wrapped executor called")
+	}
+
+	//拦截器返回的错误终止执行
+	errInterceptor := &errorInterceptor{}
+	mgr.AddInterceptor(errInterceptor)
+	err = mgr.NewSession().Select(selectSql).Param().Result(&ret)
+	if err != errDenied {
+		t.Fatalf("expect interceptor error, get %v", err)
+	}
+	//已经创建的session不受影响
+	if err = sess.Select(selectSql).Param().Result(&ret); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var errDenied = errors.New("denied")
+
+type errorInterceptor struct {
+	plugin.BaseInterceptor
+}
+
+func (i *errorInterceptor) InterceptMetadata(ctx context.Context, sqlId string, md *sqlparser.Metadata) (*sqlparser.Metadata, error) {
+	return nil, errDenied
+}
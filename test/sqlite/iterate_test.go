@@ -0,0 +1,97 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"fmt"
+	"github.com/acmestack/gobatis"
+	"testing"
+)
+
+func TestIterate(t *testing.T) {
+	initTest(t)
+	mgr := gobatis.NewSessionManager(connect())
+	sess := mgr.NewSession()
+	for i := 1; i <= 5; i++ {
+		err := sess.Insert("INSERT INTO test_table(id, username, password) VALUES(#{0}, #{1}, #{2})").
+			Param(i, fmt.Sprintf("user%d", i), fmt.Sprintf("pw%d", i)).Result(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("all", func(t *testing.T) {
+		var ids []int64
+		testV := TestTable{}
+		err := sess.Select("SELECT * FROM test_table ORDER BY id").Param().Iterate(&testV, func(idx int64, bean interface{}) bool {
+			v := bean.(*TestTable)
+			t.Logf("idx: %d, %v\n", idx, v)
+			if v.Username != fmt.Sprintf("user%d", v.Id) {
+				t.Fail()
+			}
+			ids = append(ids, v.Id)
+			return false
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ids) != 5 {
+			t.Fatalf("expect 5 rows, get %d", len(ids))
+		}
+	})
+
+	t.Run("break", func(t *testing.T) {
+		count := 0
+		testV := TestTable{}
+		err := sess.Select("SELECT * FROM test_table ORDER BY id").Param().Iterate(&testV, func(idx int64, bean interface{}) bool {
+			count++
+			return idx == 1
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 2 {
+			t.Fatalf("expect break after 2 rows, get %d", count)
+		}
+	})
+
+	t.Run("simple type", func(t *testing.T) {
+		var sum int64
+		var id int64
+		err := sess.Select("SELECT id FROM test_table").Param().Iterate(&id, func(idx int64, bean interface{}) bool {
+			sum += *bean.(*int64)
+			return false
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sum != 15 {
+			t.Fatalf("expect sum 15, get %d", sum)
+		}
+	})
+
+	t.Run("not select", func(t *testing.T) {
+		testV := TestTable{}
+		err := sess.Delete("DELETE FROM test_table").Param().Iterate(&testV, func(idx int64, bean interface{}) bool {
+			return false
+		})
+		if err == nil {
+			t.Fatal("expect error")
+		}
+	})
+}
@@ -0,0 +1,111 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"errors"
+	"github.com/acmestack/gobatis"
+	"github.com/acmestack/gobatis/datasource"
+	"testing"
+)
+
+func TestLocalCache(t *testing.T) {
+	initTest(t)
+	fac := gobatis.NewFactory(
+		gobatis.SetMaxConn(100),
+		gobatis.SetMaxIdleConn(50),
+		gobatis.SetLocalCache(true),
+		gobatis.SetDataSource(&datasource.SqliteDataSource{
+			Path: "test.db",
+		}))
+	defer fac.Close()
+	mgr := gobatis.NewSessionManager(fac)
+	insertSql := "INSERT INTO test_table(username, password) VALUES(#{0}, #{1})"
+	selectSql := "SELECT * FROM test_table WHERE username = #{0}"
+
+	sess := mgr.NewSession()
+	if err := sess.Insert(insertSql).Param("user", "pw").Result(nil); err != nil {
+		t.Fatal(err)
+	}
+	var first []TestTable
+	if err := sess.Select(selectSql).Param("user").Result(&first); err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 {
+		t.Fatal("expect 1 row")
+	}
+
+	//其他session的修改不会清空当前session的缓存
+	if err := mgr.NewSession().Insert(insertSql).Param("user", "pw2").Result(nil); err != nil {
+		t.Fatal(err)
+	}
+	var cached []TestTable
+	if err := sess.Select(selectSql).Param("user").Result(&cached); err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 1 || cached[0].Username != "user" || cached[0].Password != "pw" {
+		t.Fatalf("expect cached result, get %v", cached)
+	}
+	var single TestTable
+	if err := sess.Select(selectSql).Param("user").Result(&single); err != nil {
+		t.Fatal(err)
+	}
+	if single.Id != first[0].Id {
+		t.Fatalf("expect cached result, get %v", single)
+	}
+	//不同参数不命中缓存
+	count := 0
+	if err := sess.Select("SELECT count(*) FROM test_table WHERE username = #{0}").Param("user").Result(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expect 2 rows, get %d", count)
+	}
+
+	t.Run("clear on update", func(t *testing.T) {
+		if err := sess.Update("UPDATE test_table SET password = #{0} WHERE id = #{1}").Param("new", first[0].Id).Result(nil); err != nil {
+			t.Fatal(err)
+		}
+		var ret []TestTable
+		if err := sess.Select(selectSql).Param("user").Result(&ret); err != nil {
+			t.Fatal(err)
+		}
+		if len(ret) != 2 || ret[0].Password != "new" {
+			t.Fatalf("expect cache cleared, get %v", ret)
+		}
+	})
+
+	t.Run("clear on rollback", func(t *testing.T) {
+		sess.Tx(func(session *gobatis.Session) error {
+			session.Delete("DELETE FROM test_table").Param().Result(nil)
+			var ret []TestTable
+			session.Select(selectSql).Param("user").Result(&ret)
+			if len(ret) != 0 {
+				t.Fatal("expect deleted in tx")
+			}
+			return errors.New("rollback")
+		})
+		var ret []TestTable
+		if err := sess.Select(selectSql).Param("user").Result(&ret); err != nil {
+			t.Fatal(err)
+		}
+		if len(ret) != 2 {
+			t.Fatalf("expect cache cleared after rollback, get %v", ret)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/acmestack/gobatis"
+	gobatiserrors "github.com/acmestack/gobatis/errors"
+	"testing"
+)
+
+const proxyMapper = `<mapper namespace="proxyTest">
+    <select id="selectAll">SELECT * FROM test_table ORDER BY id</select>
+    <select id="selectById">SELECT * FROM test_table WHERE id = #{0}</select>
+    <select id="count">SELECT count(*) FROM test_table</select>
+    <insert id="insert">INSERT INTO test_table(username, password) VALUES(#{TestTable.username}, #{TestTable.password})</insert>
+    <update id="updatePassword">UPDATE test_table SET password = #{1} WHERE id = #{0}</update>
+    <delete id="deleteById">DELETE FROM test_table WHERE id = #{0}</delete>
+</mapper>`
+
+type TestTableMapper struct {
+	SelectAll      func(ctx context.Context) ([]TestTable, error)                `statement:"proxyTest.selectAll"`
+	SelectById     func(id int64) (*TestTable, error)                            `statement:"proxyTest.selectById"`
+	Count          func() (int, error)                                           `statement:"proxyTest.count"`
+	Insert         func(ctx context.Context, v TestTable) (int64, int64, error)  `statement:"proxyTest.insert"`
+	UpdatePassword func(ctx context.Context, id int64, pw string) (int64, error) `statement:"proxyTest.updatePassword"`
+	DeleteById     func(id int64) error                                          `statement:"proxyTest.deleteById"`
+	Ignore         func()
+}
+
+func TestBindMapper(t *testing.T) {
+	initTest(t)
+	if err := gobatis.RegisterMapperData([]byte(proxyMapper)); err != nil {
+		t.Fatal(err)
+	}
+	fac := connect()
+	defer fac.Close()
+	mgr := gobatis.NewSessionManager(fac)
+
+	var mapper TestTableMapper
+	if err := mgr.BindMapper(&mapper); err != nil {
+		t.Fatal(err)
+	}
+	if mapper.Ignore != nil {
+		t.Fatal("expect field without tag ignored")
+	}
+
+	ctx := context.Background()
+	count, id, err := mapper.Insert(ctx, TestTable{Username: "user1", Password: "pw1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || id == 0 {
+		t.Fatalf("expect insert count 1 and id, get %d %d", count, id)
+	}
+	count, err = mapper.UpdatePassword(ctx, id, "pw2")
+	if err != nil || count != 1 {
+		t.Fatalf("expect update 1 row, get %d %v", count, err)
+	}
+	v, err := mapper.SelectById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Username != "user1" || v.Password != "pw2" {
+		t.Fatalf("expect updated row, get %v", v)
+	}
+
+	t.Run("tx", func(t *testing.T) {
+		err := mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			ctx := gobatis.WithSession(context.Background(), sess)
+			if _, _, err := mapper.Insert(ctx, TestTable{Username: "user2", Password: "pw"}); err != nil {
+				return err
+			}
+			list, err := mapper.SelectAll(ctx)
+			if err != nil {
+				return err
+			}
+			if len(list) != 2 {
+				t.Fatalf("expect 2 rows in tx, get %d", len(list))
+			}
+			return errors.New("rollback")
+		})
+		if err == nil {
+			t.Fatal("expect error")
+		}
+		if n, err := mapper.Count(); err != nil || n != 1 {
+			t.Fatalf("expect rollback, get %d %v", n, err)
+		}
+	})
+
+	if err := mapper.DeleteById(id); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := mapper.Count(); err != nil || n != 0 {
+		t.Fatalf("expect deleted, get %d %v", n, err)
+	}
+
+	t.Run("error", func(t *testing.T) {
+		var notFound struct {
+			Select func() ([]TestTable, error) `statement:"proxyTest.notExist"`
+		}
+		if err := mgr.BindMapper(&notFound); !errors.Is(err, gobatiserrors.StatementNotFound) {
+			t.Fatalf("expect statement not found, get %v", err)
+		}
+		var badFunc struct {
+			Update func(id int64) (string, error) `statement:"proxyTest.updatePassword"`
+		}
+		if err := mgr.BindMapper(&badFunc); !errors.Is(err, gobatiserrors.MapperFuncNotSupport) {
+			t.Fatalf("expect func not support, get %v", err)
+		}
+		if err := mgr.BindMapper(mapper); !errors.Is(err, gobatiserrors.MapperNotStructPointer) {
+			t.Fatalf("expect not struct pointer, get %v", err)
+		}
+	})
+}
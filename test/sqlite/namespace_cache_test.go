@@ -0,0 +1,182 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"errors"
+	"github.com/acmestack/gobatis"
+	"github.com/acmestack/gobatis/cache"
+	"github.com/acmestack/gobatis/datasource"
+	gobatiserrors "github.com/acmestack/gobatis/errors"
+	"os"
+	"testing"
+)
+
+var cacheMapper = `<?xml version="1.0" encoding="UTF-8"?>
+<mapper namespace="cacheTest">
+    <cache eviction="LRU" size="2" flushInterval="60000"/>
+    <select id="selectByName">
+        SELECT * FROM test_table WHERE username = #{0}
+    </select>
+    <select id="selectNoCache" useCache="false">
+        SELECT * FROM test_table WHERE username = #{0}
+    </select>
+    <update id="updatePassword">
+        UPDATE test_table SET password = #{0} WHERE username = #{1}
+    </update>
+    <update id="updateNoFlush" flushCache="false">
+        UPDATE test_table SET password = #{0} WHERE username = #{1}
+    </update>
+</mapper>`
+
+func TestNamespaceCache(t *testing.T) {
+	initTest(t)
+	if err := gobatis.RegisterMapperData([]byte(cacheMapper)); err != nil {
+		t.Fatal(err)
+	}
+	fac := connect()
+	defer fac.Close()
+	mgr := gobatis.NewSessionManager(fac)
+	if err := mgr.NewSession().Insert("INSERT INTO test_table(username, password) VALUES(#{0}, #{1})").Param("user", "pw").Result(nil); err != nil {
+		t.Fatal(err)
+	}
+	updateSql := "UPDATE test_table SET password = #{0} WHERE username = #{1}"
+	selectPassword := func(sqlId string) string {
+		var ret TestTable
+		if err := mgr.NewSession().Select(sqlId).Param("user").Result(&ret); err != nil {
+			t.Fatal(err)
+		}
+		return ret.Password
+	}
+
+	if selectPassword("cacheTest.selectByName") != "pw" {
+		t.Fatal("expect pw")
+	}
+	//不在namespace中的修改不会清空缓存
+	if err := mgr.NewSession().Update(updateSql).Param("pw2", "user").Result(nil); err != nil {
+		t.Fatal(err)
+	}
+	if selectPassword("cacheTest.selectByName") != "pw" {
+		t.Fatal("expect cached pw across sessions")
+	}
+	if selectPassword("cacheTest.selectNoCache") != "pw2" {
+		t.Fatal("expect useCache false query database")
+	}
+	if err := mgr.NewSession().Update("cacheTest.updateNoFlush").Param("pw3", "user").Result(nil); err != nil {
+		t.Fatal(err)
+	}
+	if selectPassword("cacheTest.selectByName") != "pw" {
+		t.Fatal("expect flushCache false keep cache")
+	}
+	if err := mgr.NewSession().Update("cacheTest.updatePassword").Param("pw4", "user").Result(nil); err != nil {
+		t.Fatal(err)
+	}
+	if selectPassword("cacheTest.selectByName") != "pw4" {
+		t.Fatal("expect cache flushed by namespace update")
+	}
+
+	t.Run("tx", func(t *testing.T) {
+		mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			sess.Update("cacheTest.updatePassword").Param("pw5", "user").Result(nil)
+			var ret TestTable
+			sess.Select("cacheTest.selectByName").Param("user").Result(&ret)
+			if ret.Password != "pw5" {
+				t.Fatal("expect uncommitted value in tx")
+			}
+			return errors.New("rollback")
+		})
+		if selectPassword("cacheTest.selectByName") != "pw4" {
+			t.Fatal("expect uncommitted value not cached")
+		}
+	})
+
+	t.Run("tx bypass flushed namespace", func(t *testing.T) {
+		mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			sess.Update("cacheTest.updatePassword").Param("pw6", "user").Result(nil)
+			//事务提交前其他session将已提交的值写入缓存
+			if selectPassword("cacheTest.selectByName") != "pw4" {
+				t.Fatal("expect committed value outside tx")
+			}
+			var ret TestTable
+			sess.Select("cacheTest.selectByName").Param("user").Result(&ret)
+			if ret.Password != "pw6" {
+				t.Fatalf("expect uncommitted value in tx, get %s", ret.Password)
+			}
+			return errors.New("rollback")
+		})
+	})
+
+	t.Run("data source in key", func(t *testing.T) {
+		defer os.Remove("test_cache.db")
+		otherFac := gobatis.NewFactory(gobatis.SetDataSource(&datasource.SqliteDataSource{
+			Path: "test_cache.db",
+		}))
+		defer otherFac.Close()
+		other := gobatis.NewSessionManager(otherFac)
+		err := other.NewSession().Exec("CREATE TABLE IF NOT EXISTS test_table (id INTEGER PRIMARY KEY AUTOINCREMENT, username VARCHAR(64), password VARCHAR(64))").Param().Result(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = other.NewSession().Insert("INSERT INTO test_table(username, password) VALUES(#{0}, #{1})").Param("user", "other").Result(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if selectPassword("cacheTest.selectByName") != "pw4" {
+			t.Fatal("expect pw4")
+		}
+		var ret TestTable
+		if err := other.NewSession().Select("cacheTest.selectByName").Param("user").Result(&ret); err != nil {
+			t.Fatal(err)
+		}
+		if ret.Password != "other" {
+			t.Fatalf("expect result of other data source, get %s", ret.Password)
+		}
+	})
+
+	t.Run("query one", func(t *testing.T) {
+		if err := mgr.NewSession().Update(updateSql).Param("pw7", "user").Result(nil); err != nil {
+			t.Fatal(err)
+		}
+		ret, err := gobatis.QueryOne[TestTable](mgr.NewSession(), "cacheTest.selectByName", "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret.Password != "pw4" {
+			t.Fatalf("expect cached pw4 through QueryOne, get %s", ret.Password)
+		}
+	})
+
+	t.Run("custom cache", func(t *testing.T) {
+		var created cache.Config
+		cache.RegisterCache("testCache", func(config cache.Config) (cache.Cache, error) {
+			created = config
+			return cache.NewLruCache(config.Size), nil
+		})
+		err := gobatis.RegisterMapperData([]byte(`<mapper namespace="customCacheTest"><cache type="testCache" size="10"/></mapper>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if created.Namespace != "customCacheTest" || created.Size != 10 {
+			t.Fatalf("expect custom cache created, get %v", created)
+		}
+		err = gobatis.RegisterMapperData([]byte(`<mapper namespace="badCacheTest"><cache type="notExist"/></mapper>`))
+		if err != gobatiserrors.CacheTypeNotSupport {
+			t.Fatalf("expect cache type not support, get %v", err)
+		}
+	})
+}
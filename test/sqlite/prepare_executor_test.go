@@ -0,0 +1,103 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"fmt"
+	"github.com/acmestack/gobatis"
+	"github.com/acmestack/gobatis/datasource"
+	gobatiserrors "github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/executor"
+	"github.com/acmestack/gobatis/factory"
+	"testing"
+)
+
+func TestPrepareExecutor(t *testing.T) {
+	initTest(t)
+	fac := gobatis.NewFactory(
+		gobatis.SetMaxConn(100),
+		gobatis.SetMaxIdleConn(50),
+		gobatis.SetExecutorType(executor.TypePrepare),
+		gobatis.SetStmtCacheSize(2),
+		gobatis.SetDataSource(&datasource.SqliteDataSource{
+			Path: "test.db",
+		}))
+	defer fac.Close()
+	mgr := gobatis.NewSessionManager(fac)
+	stats := fac.(*factory.DefaultFactory).StmtCacheStats
+	insertSql := "INSERT INTO test_table(username, password) VALUES(#{0}, #{1})"
+
+	sess := mgr.NewSession()
+	for i := 0; i < 3; i++ {
+		err := sess.Insert(insertSql).Param(fmt.Sprintf("user%d", i), "pw").Result(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := stats()
+	t.Logf("after insert: %+v", s)
+	if s.Hits != 2 || s.Misses != 1 {
+		t.Fatalf("expect 2 hits 1 miss, get %+v", s)
+	}
+
+	t.Run("tx", func(t *testing.T) {
+		err := mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			for i := 0; i < 2; i++ {
+				err := sess.Insert(insertSql).Param("tx_user", "pw").Result(nil)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := stats()
+		t.Logf("after tx: %+v", s)
+		if s.Hits != 3 || s.Misses != 2 {
+			t.Fatalf("expect 3 hits 2 misses, get %+v", s)
+		}
+		if countTestTable(t, mgr) != 5 {
+			t.Fatal("expect 5 rows")
+		}
+	})
+
+	t.Run("evict", func(t *testing.T) {
+		before := stats()
+		sess.Select("SELECT count(username) FROM test_table").Param().Result(new(int))
+		sess.Select("SELECT count(password) FROM test_table").Param().Result(new(int))
+		//insert statement was evicted
+		sess.Insert(insertSql).Param("user", "pw").Result(nil)
+		s := stats()
+		if s.Misses-before.Misses != 3 {
+			t.Fatalf("expect 3 misses, get %+v", s)
+		}
+	})
+
+	t.Run("prepare error", func(t *testing.T) {
+		err := sess.Select("SELECT * FROM not_exist_table").Param().Result(new(int))
+		if err == nil {
+			t.Fatal("expect error")
+		}
+		t.Log(err)
+		if err == gobatiserrors.ConnectionPrepareError {
+			t.Fatal("expect driver error")
+		}
+	})
+}
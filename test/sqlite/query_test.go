@@ -0,0 +1,100 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/acmestack/gobatis"
+	gobatiserrors "github.com/acmestack/gobatis/errors"
+	"testing"
+)
+
+func TestQueryHelpers(t *testing.T) {
+	initTest(t)
+	fac := connect()
+	defer fac.Close()
+	sess := gobatis.NewSessionManager(fac).NewSession()
+
+	insertSql := "INSERT INTO test_table(id, username, password) VALUES(#{0}, #{1}, #{2})"
+	for i, name := range []string{"user1", "user2", "user2"} {
+		n, err := gobatis.Exec(sess, insertSql, i+1, name, fmt.Sprintf("pw%d", i+1))
+		if err != nil || n != 1 {
+			t.Fatalf("expect insert 1 row, get %d %v", n, err)
+		}
+	}
+
+	list, err := gobatis.QueryList[TestTable](sess, "SELECT * FROM test_table ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[2].Password != "pw3" {
+		t.Fatalf("expect 3 rows, get %v", list)
+	}
+
+	v, err := gobatis.QueryOne[*TestTable](sess, "SELECT * FROM test_table WHERE id = #{0}", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Username != "user2" {
+		t.Fatalf("expect user2, get %v", v)
+	}
+	first, err := gobatis.QueryOne[TestTable](sess, "SELECT * FROM test_table ORDER BY id")
+	if err != nil || first.Id != 1 {
+		t.Fatalf("expect first row, get %v %v", first, err)
+	}
+	name, err := gobatis.QueryOne[string](sess, "SELECT username FROM test_table ORDER BY id DESC")
+	if err != nil || name != "user2" {
+		t.Fatalf("expect user2, get %v %v", name, err)
+	}
+	_, err = gobatis.QueryOne[TestTable](sess, "SELECT * FROM test_table WHERE id = #{0}", 100)
+	if !errors.Is(err, gobatiserrors.ResultSelectEmptyValue) {
+		t.Fatalf("expect not found, get %v", err)
+	}
+
+	counts, err := gobatis.QueryMap[string, int](sess, "SELECT username, count(*) AS num FROM test_table GROUP BY username", "username")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts["user1"] != 1 || counts["user2"] != 2 {
+		t.Fatalf("expect counts by username, get %v", counts)
+	}
+	_, err = gobatis.QueryMap[string, int](sess, "SELECT username, count(*) AS num, max(id) AS max_id FROM test_table GROUP BY username", "username")
+	if !errors.Is(err, gobatiserrors.ResultMapValueColumnError) {
+		t.Fatalf("expect value column error, get %v", err)
+	}
+	_, err = gobatis.QueryMap[string, string](sess, "SELECT username FROM test_table", "username")
+	if !errors.Is(err, gobatiserrors.ResultMapValueColumnError) {
+		t.Fatalf("expect value column error, get %v", err)
+	}
+	rows, err := gobatis.QueryMap[int64, TestTable](sess, "SELECT * FROM test_table", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[3].Username != "user2" || rows[3].Id != 3 {
+		t.Fatalf("expect rows by id, get %v", rows)
+	}
+	if _, err := gobatis.QueryMap[int64, TestTable](sess, "SELECT * FROM test_table", "notExist"); !errors.Is(err, gobatiserrors.ResultNameNotFound) {
+		t.Fatalf("expect key column not found, get %v", err)
+	}
+
+	n, err := gobatis.Exec(sess, "DELETE FROM test_table WHERE username = #{0}", "user2")
+	if err != nil || n != 2 {
+		t.Fatalf("expect delete 2 rows, get %d %v", n, err)
+	}
+}
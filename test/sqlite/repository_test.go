@@ -0,0 +1,154 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/acmestack/gobatis"
+	gobatiserrors "github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/reflection"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRepository(t *testing.T) {
+	initTest(t)
+	fac := connect()
+	defer fac.Close()
+	mgr := gobatis.NewSessionManager(fac)
+	repo, err := gobatis.NewRepository[TestTable](mgr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	v := TestTable{Username: "user1", Password: "pw1"}
+	if n, err := repo.Insert(ctx, &v); err != nil || n != 1 || v.Id == 0 {
+		t.Fatalf("expect insert with generated id, get %d %d %v", n, v.Id, err)
+	}
+	list := []TestTable{{Username: "user2", Password: "pw2"}, {Username: "user3", Password: "pw2"}}
+	if n, err := repo.BatchInsert(ctx, list); err != nil || n != 2 || list[0].Id == 0 || list[1].Id != list[0].Id+1 {
+		t.Fatalf("expect batch insert with generated ids, get %d %v %v", n, list, err)
+	}
+
+	if n, err := repo.UpdateNonZeroById(ctx, TestTable{Id: v.Id, Password: "new"}); err != nil || n != 1 {
+		t.Fatalf("expect update 1 row, get %d %v", n, err)
+	}
+	ret, err := repo.SelectById(ctx, v.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Username != "user1" || ret.Password != "new" {
+		t.Fatalf("expect only non-zero field updated, get %v", ret)
+	}
+	if n, err := repo.UpdateById(ctx, TestTable{Id: v.Id, Username: "user1"}); err != nil || n != 1 {
+		t.Fatalf("expect update 1 row, get %d %v", n, err)
+	}
+	ret, err = repo.SelectById(ctx, v.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Password != "" {
+		t.Fatalf("expect all fields updated, get %v", ret)
+	}
+
+	examples, err := repo.SelectByExample(ctx, TestTable{Password: "pw2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) != 2 {
+		t.Fatalf("expect 2 rows, get %v", examples)
+	}
+	all, err := repo.SelectByExample(ctx, TestTable{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("expect all rows, get %v %v", all, err)
+	}
+
+	t.Run("tx", func(t *testing.T) {
+		err := mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			ctx := gobatis.WithSession(context.Background(), sess)
+			if _, err := repo.DeleteById(ctx, v.Id); err != nil {
+				return err
+			}
+			if _, err := repo.SelectById(ctx, v.Id); !errors.Is(err, gobatiserrors.ResultSelectEmptyValue) {
+				t.Fatalf("expect deleted in tx, get %v", err)
+			}
+			return errors.New("rollback")
+		})
+		if err == nil {
+			t.Fatal("expect error")
+		}
+		if _, err := repo.SelectById(ctx, v.Id); err != nil {
+			t.Fatalf("expect rollback, get %v", err)
+		}
+	})
+
+	t.Run("identifier quote", func(t *testing.T) {
+		gobatis.RegisterIdentifierQuote("sqlite3", "[", "]")
+		defer gobatis.RegisterIdentifierQuote("sqlite3", `"`, `"`)
+
+		config := gobatis.NewConfiguration()
+		quoted, err := gobatis.NewRepository[TestTable](gobatis.NewSessionManager(fac, config))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ret, err := quoted.SelectById(ctx, v.Id); err != nil || ret.Username != "user1" {
+			t.Fatalf("expect select with bracket quote, get %v %v", ret, err)
+		}
+		parser, ok := config.FindSqlParser("gobatis.repository.sqlite3." + reflection.GetTypeClassName(reflect.TypeOf(TestTable{})) + ".selectById")
+		if !ok {
+			t.Fatal("expect repository statement registered")
+		}
+		md, err := parser.ParseMetadata("sqlite3", v.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(md.PrepareSql, "[id] = ?") {
+			t.Fatalf("expect open and close quote, get %s", md.PrepareSql)
+		}
+	})
+
+	if n, err := repo.DeleteById(ctx, v.Id); err != nil || n != 1 {
+		t.Fatalf("expect delete 1 row, get %d %v", n, err)
+	}
+	if _, err := repo.DeleteById(ctx); !errors.Is(err, gobatiserrors.ParseSqlParamVarNumberError) {
+		t.Fatalf("expect id number error, get %v", err)
+	}
+	type noKeyTable struct {
+		Id int64 `column:"id"`
+	}
+	if _, err := gobatis.NewRepository[noKeyTable](mgr); !errors.Is(err, gobatiserrors.PrimaryKeyNotFound) {
+		t.Fatalf("expect primary key not found, get %v", err)
+	}
+	type autoOnlyTable struct {
+		Id  int64 `column:"id,pk,auto"`
+		Seq int64 `column:"seq,auto"`
+	}
+	if _, err := gobatis.NewRepository[autoOnlyTable](mgr); !errors.Is(err, gobatiserrors.ModelColumnsNotEnough) {
+		t.Fatalf("expect no insertable column, get %v", err)
+	}
+	type keyOnlyTable struct {
+		Id   int64  `column:"id,pk"`
+		Name string `column:"name,pk"`
+	}
+	if _, err := gobatis.NewRepository[keyOnlyTable](mgr); !errors.Is(err, gobatiserrors.ModelColumnsNotEnough) {
+		t.Fatalf("expect no updatable column, get %v", err)
+	}
+}
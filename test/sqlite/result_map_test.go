@@ -0,0 +1,167 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"database/sql"
+	"github.com/acmestack/gobatis"
+	gobatiserrors "github.com/acmestack/gobatis/errors"
+	"testing"
+)
+
+var resultMapMapper = `<?xml version="1.0" encoding="UTF-8"?>
+<mapper namespace="resultMapTest">
+    <resultMap id="authorResult">
+        <id property="Id" column="id"/>
+        <result property="Name" column="name"/>
+    </resultMap>
+    <resultMap id="blogResult">
+        <id property="Id" column="blog_id"/>
+        <result property="Title" column="blog_title"/>
+        <association property="Author" resultMap="authorResult" columnPrefix="author_"/>
+        <collection property="Posts">
+            <id property="Id" column="post_id"/>
+            <result property="Subject" column="post_subject"/>
+            <result property="Kind" column="post_kind"/>
+            <discriminator column="post_kind">
+                <case value="draft">
+                    <result property="Note" column="post_note"/>
+                </case>
+            </discriminator>
+        </collection>
+    </resultMap>
+    <select id="selectBlogs" resultMap="blogResult">
+        SELECT b.id AS blog_id, b.title AS blog_title,
+               a.id AS author_id, a.name AS author_name,
+               p.id AS post_id, p.subject AS post_subject, p.kind AS post_kind, p.note AS post_note
+        FROM rm_blog b
+        LEFT JOIN rm_author a ON a.id = b.author_id
+        LEFT JOIN rm_post p ON p.blog_id = b.id
+        ORDER BY b.id, p.id
+    </select>
+</mapper>`
+
+type RmAuthor struct {
+	Id   int64
+	Name string
+}
+
+type RmPost struct {
+	Id      int64
+	Subject string
+	Kind    string
+	Note    string
+}
+
+type RmBlog struct {
+	Id     int64
+	Title  string
+	Author *RmAuthor
+	Posts  []RmPost
+}
+
+func TestResultMap(t *testing.T) {
+	db, err := sql.Open("sqlite3", "./test.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	stmts := []string{
+		"DROP TABLE IF EXISTS rm_blog",
+		"DROP TABLE IF EXISTS rm_author",
+		"DROP TABLE IF EXISTS rm_post",
+		"CREATE TABLE rm_blog (id INTEGER PRIMARY KEY, title VARCHAR(64), author_id INTEGER)",
+		"CREATE TABLE rm_author (id INTEGER PRIMARY KEY, name VARCHAR(64))",
+		"CREATE TABLE rm_post (id INTEGER PRIMARY KEY, blog_id INTEGER, subject VARCHAR(64), kind VARCHAR(16), note VARCHAR(64))",
+		"INSERT INTO rm_author VALUES (1, 'alice')",
+		"INSERT INTO rm_blog VALUES (1, 'go', 1), (2, 'empty', NULL)",
+		"INSERT INTO rm_post VALUES (1, 1, 'first', 'published', 'n1'), (2, 1, 'second', 'draft', 'n2')",
+	}
+	for _, s := range stmts {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gobatis.RegisterMapperData([]byte(resultMapMapper)); err != nil {
+		t.Fatal(err)
+	}
+	fac := connect()
+	defer fac.Close()
+	mgr := gobatis.NewSessionManager(fac)
+
+	var blogs []RmBlog
+	err = mgr.NewSession().Select("resultMapTest.selectBlogs").Param().Result(&blogs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blogs) != 2 {
+		t.Fatalf("expect 2 blogs grouped by id, get %v", blogs)
+	}
+	b := blogs[0]
+	if b.Title != "go" || b.Author == nil || b.Author.Id != 1 || b.Author.Name != "alice" {
+		t.Fatalf("expect association, get %v", b)
+	}
+	if len(b.Posts) != 2 || b.Posts[0].Subject != "first" || b.Posts[1].Subject != "second" {
+		t.Fatalf("expect collection, get %v", b.Posts)
+	}
+	if b.Posts[0].Note != "" || b.Posts[1].Note != "n2" {
+		t.Fatalf("expect discriminator case mapping, get %v", b.Posts)
+	}
+	if blogs[1].Author != nil || len(blogs[1].Posts) != 0 {
+		t.Fatalf("expect empty nested result, get %v", blogs[1])
+	}
+
+	var blog RmBlog
+	err = mgr.NewSession().Select("resultMapTest.selectBlogs").Param().Result(&blog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blog.Id != 1 || len(blog.Posts) != 2 {
+		t.Fatalf("expect first blog, get %v", blog)
+	}
+
+	var ret map[string]interface{}
+	err = mgr.NewSession().Select("resultMapTest.selectBlogs").Param().Result(&ret)
+	if err != gobatiserrors.ResultMapTypeNotSupport {
+		t.Fatalf("expect type not support, get %v", err)
+	}
+
+	var badBlogs []struct {
+		Id     int64
+		Author string
+	}
+	err = mgr.NewSession().Select("resultMapTest.selectBlogs").Param().Result(&badBlogs)
+	if err != gobatiserrors.ResultMapPropertyTypeError {
+		t.Fatalf("expect association type error, get %v", err)
+	}
+
+	err = mgr.NewSession().Select("resultMapTest.selectBlogs").Param().Iterate(&blog, func(idx int64, bean interface{}) bool {
+		return false
+	})
+	if err != gobatiserrors.IterateResultMapNotSupport {
+		t.Fatalf("expect iterate not support, get %v", err)
+	}
+
+	one, err := gobatis.QueryOne[RmBlog](mgr.NewSession(), "resultMapTest.selectBlogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if one.Id != 1 || len(one.Posts) != 2 {
+		t.Fatalf("expect first blog with all posts, get %v", one)
+	}
+}
@@ -30,7 +30,7 @@ import (
 
 type TestTable struct {
 	TestTable gobatis.TableName "test_table"
-	Id        int64             `column:"id"`
+	Id        int64             `column:"id,pk,auto"`
 	Username  string            `column:"username"`
 	Password  string            `column:"password"`
 }
@@ -259,3 +259,12 @@ func TestTx3(t *testing.T) {
 		return nil
 	})
 }
+
+func countTestTable(t *testing.T, mgr *gobatis.SessionManager) int {
+	count := 0
+	err := mgr.NewSession().Select("SELECT count(*) FROM test_table").Param().Result(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
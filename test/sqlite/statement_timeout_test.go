@@ -0,0 +1,104 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"context"
+	"github.com/acmestack/gobatis"
+	"github.com/acmestack/gobatis/executor"
+	"github.com/acmestack/gobatis/plugin"
+	"github.com/acmestack/gobatis/reflection"
+	"testing"
+	"time"
+)
+
+var timeoutMapper = `<?xml version="1.0" encoding="UTF-8"?>
+<mapper namespace="timeoutTest">
+    <select id="selectFetch" timeout="2" fetchSize="50">
+        SELECT count(*) FROM test_table
+    </select>
+    <select id="selectInvalid" timeout="abc">
+        SELECT count(*) FROM test_table
+    </select>
+    <select id="selectSlow" timeout="10ms">
+        WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x &lt; 100000000) SELECT count(*) FROM c
+    </select>
+</mapper>`
+
+type contextExecutor struct {
+	executor.Executor
+	ctx context.Context
+}
+
+func (e *contextExecutor) Query(ctx context.Context, result reflection.Object, sql string, params ...interface{}) error {
+	e.ctx = ctx
+	return e.Executor.Query(ctx, result, sql, params...)
+}
+
+type contextInterceptor struct {
+	plugin.BaseInterceptor
+	e *contextExecutor
+}
+
+func (i *contextInterceptor) WrapExecutor(e executor.Executor) executor.Executor {
+	i.e = &contextExecutor{Executor: e}
+	return i.e
+}
+
+func TestStatementTimeout(t *testing.T) {
+	initTest(t)
+	if err := gobatis.RegisterMapperData([]byte(timeoutMapper)); err != nil {
+		t.Fatal(err)
+	}
+	fac := connect()
+	defer fac.Close()
+	mgr := gobatis.NewSessionManager(fac)
+	interceptor := &contextInterceptor{}
+	mgr.AddInterceptor(interceptor)
+
+	count := 0
+	err := mgr.NewSession().Select("timeoutTest.selectFetch").Param().Result(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline, ok := interceptor.e.ctx.Deadline()
+	if !ok || time.Until(deadline) > 2*time.Second {
+		t.Fatal("expect deadline from timeout attribute")
+	}
+	if executor.FetchSize(interceptor.e.ctx) != 50 {
+		t.Fatal("expect fetchSize passed by context")
+	}
+
+	err = mgr.NewSession().Select("timeoutTest.selectInvalid").Param().Result(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := interceptor.e.ctx.Deadline(); ok {
+		t.Fatal("expect invalid timeout ignored")
+	}
+
+	start := time.Now()
+	err = mgr.NewSession().Select("timeoutTest.selectSlow").Param().Result(&count)
+	if err == nil {
+		t.Fatal("expect timeout error")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("expect query interrupted")
+	}
+	t.Log(err)
+}
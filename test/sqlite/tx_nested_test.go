@@ -0,0 +1,114 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/acmestack/gobatis"
+	"testing"
+)
+
+func TestTxNested(t *testing.T) {
+	mgr := gobatis.NewSessionManager(connect())
+	insertSql := "INSERT INTO test_table(username, password) VALUES(#{0}, #{1})"
+
+	t.Run("rollback inner only", func(t *testing.T) {
+		initTest(t)
+		err := mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			for i := 0; i < 3; i++ {
+				nestedErr := sess.Nested(func(sess *gobatis.Session) error {
+					err := sess.Insert(insertSql).Param(fmt.Sprintf("user%d", i), "pw").Result(nil)
+					if err != nil {
+						return err
+					}
+					if i == 1 {
+						return errors.New("bad record")
+					}
+					return nil
+				})
+				if nestedErr != nil {
+					t.Log(nestedErr)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if countTestTable(t, mgr) != 2 {
+			t.Fatal("expect 2 rows")
+		}
+	})
+
+	t.Run("outer rollback", func(t *testing.T) {
+		initTest(t)
+		mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			err := sess.Nested(func(sess *gobatis.Session) error {
+				return sess.Insert(insertSql).Param("user", "pw").Result(nil)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return errors.New("rollback outer")
+		})
+		if countTestTable(t, mgr) != 0 {
+			t.Fatal("expect 0 rows")
+		}
+	})
+
+	t.Run("joined tx fails in nested", func(t *testing.T) {
+		initTest(t)
+		err := mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			if err := sess.Insert(insertSql).Param("outer", "pw").Result(nil); err != nil {
+				return err
+			}
+			nestedErr := sess.Nested(func(sess *gobatis.Session) error {
+				return sess.Tx(func(sess *gobatis.Session) error {
+					if err := sess.Insert(insertSql).Param("inner", "pw").Result(nil); err != nil {
+						return err
+					}
+					return errors.New("inner failed")
+				})
+			})
+			if nestedErr == nil {
+				t.Fatal("expect nested error")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expect outer committed, get %v", err)
+		}
+		if countTestTable(t, mgr) != 1 {
+			t.Fatal("expect only outer row")
+		}
+	})
+
+	t.Run("without transaction", func(t *testing.T) {
+		initTest(t)
+		err := mgr.NewSession().Nested(func(sess *gobatis.Session) error {
+			return sess.Insert(insertSql).Param("user", "pw").Result(nil)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if countTestTable(t, mgr) != 1 {
+			t.Fatal("expect 1 row")
+		}
+	})
+}
@@ -0,0 +1,83 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"context"
+	"database/sql"
+	"github.com/acmestack/gobatis"
+	"testing"
+	"time"
+)
+
+func TestTxWithOptions(t *testing.T) {
+	mgr := gobatis.NewSessionManager(connect())
+	insertSql := "INSERT INTO test_table(username, password) VALUES(#{0}, #{1})"
+
+	t.Run("commit", func(t *testing.T) {
+		initTest(t)
+		opts := &gobatis.TxOptions{
+			Isolation: sql.LevelSerializable,
+			Timeout:   time.Second,
+		}
+		err := mgr.NewSession().TxWithOptions(context.Background(), opts, func(sess *gobatis.Session) error {
+			return sess.Insert(insertSql).Param("user", "pw").Result(nil)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if countTestTable(t, mgr) != 1 {
+			t.Fatal("expect 1 row")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		initTest(t)
+		opts := &gobatis.TxOptions{
+			Timeout: 50 * time.Millisecond,
+		}
+		err := mgr.NewSession().TxWithOptions(context.Background(), opts, func(sess *gobatis.Session) error {
+			err := sess.Insert(insertSql).Param("user", "pw").Result(nil)
+			if err != nil {
+				return err
+			}
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		})
+		if err == nil {
+			t.Fatal("expect timeout error")
+		}
+		t.Log(err)
+		if countTestTable(t, mgr) != 0 {
+			t.Fatal("expect 0 rows")
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		called := false
+		err := mgr.NewSession().TxWithOptions(ctx, nil, func(sess *gobatis.Session) error {
+			called = true
+			return nil
+		})
+		if err == nil || called {
+			t.Fatal("expect begin failed")
+		}
+	})
+}
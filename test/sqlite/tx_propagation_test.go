@@ -0,0 +1,128 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"errors"
+	"github.com/acmestack/gobatis"
+	gobatiserrors "github.com/acmestack/gobatis/errors"
+	"testing"
+)
+
+func TestTxPropagation(t *testing.T) {
+	mgr := gobatis.NewSessionManager(connect())
+	insertSql := "INSERT INTO test_table(username, password) VALUES(#{0}, #{1})"
+
+	t.Run("required join commit", func(t *testing.T) {
+		initTest(t)
+		err := mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			err := sess.Insert(insertSql).Param("outer", "pw").Result(nil)
+			if err != nil {
+				return err
+			}
+			return sess.Tx(func(sess *gobatis.Session) error {
+				return sess.Insert(insertSql).Param("inner", "pw").Result(nil)
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if countTestTable(t, mgr) != 2 {
+			t.Fatal("expect 2 rows")
+		}
+	})
+
+	t.Run("required join rollback only", func(t *testing.T) {
+		initTest(t)
+		err := mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			err := sess.Insert(insertSql).Param("outer", "pw").Result(nil)
+			if err != nil {
+				return err
+			}
+			innerErr := sess.Tx(func(sess *gobatis.Session) error {
+				return errors.New("inner failed")
+			})
+			t.Log(innerErr)
+			//ignore inner error
+			return nil
+		})
+		if err != gobatiserrors.TransactionRollbackOnly {
+			t.Fatalf("expect rollback only, get %v", err)
+		}
+		if countTestTable(t, mgr) != 0 {
+			t.Fatal("expect 0 rows")
+		}
+	})
+
+	t.Run("mandatory", func(t *testing.T) {
+		err := mgr.NewSession().TxWithPropagation(gobatis.PropagationMandatory, func(sess *gobatis.Session) error {
+			return nil
+		})
+		if err != gobatiserrors.TransactionNotExist {
+			t.Fatalf("expect TransactionNotExist, get %v", err)
+		}
+	})
+
+	t.Run("never", func(t *testing.T) {
+		var innerErr error
+		mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			innerErr = sess.TxWithPropagation(gobatis.PropagationNever, func(sess *gobatis.Session) error {
+				return nil
+			})
+			return nil
+		})
+		if innerErr != gobatiserrors.TransactionExist {
+			t.Fatalf("expect TransactionExist, get %v", innerErr)
+		}
+	})
+
+	t.Run("requires new", func(t *testing.T) {
+		initTest(t)
+		err := mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			err := sess.TxWithPropagation(gobatis.PropagationRequiresNew, func(sess *gobatis.Session) error {
+				return sess.Insert(insertSql).Param("inner", "pw").Result(nil)
+			})
+			if err != nil {
+				return err
+			}
+			return errors.New("rollback outer")
+		})
+		if err == nil {
+			t.Fatal("expect error")
+		}
+		if countTestTable(t, mgr) != 1 {
+			t.Fatal("expect inner transaction committed")
+		}
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		initTest(t)
+		mgr.NewSession().Tx(func(sess *gobatis.Session) error {
+			err := sess.TxWithPropagation(gobatis.PropagationNotSupported, func(sess *gobatis.Session) error {
+				return sess.Insert(insertSql).Param("inner", "pw").Result(nil)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return errors.New("rollback outer")
+		})
+		if countTestTable(t, mgr) != 1 {
+			t.Fatal("expect non-transactional insert kept")
+		}
+	})
+}
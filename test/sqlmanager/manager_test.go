@@ -19,7 +19,12 @@ package sqlmanager
 
 import (
 	"github.com/acmestack/gobatis"
+	gobatiserrors "github.com/acmestack/gobatis/errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestManager(t *testing.T) {
@@ -28,3 +33,143 @@ func TestManager(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestScanMapperFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"mapper/fs.xml":        {Data: []byte(`<mapper namespace="fsXml"><select id="select">SELECT 1 FROM t</select></mapper>`)},
+		"mapper/sub/fs.tpl":    {Data: []byte(`{{define "namespace"}}fsTpl{{end}}{{define "select"}}SELECT 2 FROM t{{end}}`)},
+		"mapper/sub/readme.md": {Data: []byte("ignored")},
+	}
+	if err := gobatis.ScanMapperFS(fsys, "mapper"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gobatis.FindDynamicSqlParser("fsXml.select"); !ok {
+		t.Fatal("expect xml registered")
+	}
+	if _, ok := gobatis.FindTemplateSqlParser("fsTpl.select"); !ok {
+		t.Fatal("expect template registered")
+	}
+
+	bad := fstest.MapFS{"bad.xml": {Data: []byte(`<mapper namespace="fsBad"><select id="select">SELECT #{} FROM t</select></mapper>`)}}
+	if err := gobatis.ScanMapperFS(bad, "."); err == nil {
+		t.Fatal("expect validate error")
+	}
+}
+
+func TestWatchMapperFile(t *testing.T) {
+	dir := t.TempDir()
+	xmlFile := filepath.Join(dir, "watch.xml")
+	tplFile := filepath.Join(dir, "watch.tpl")
+	write := func(file, data string) {
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prepareSql := func(sqlId string) string {
+		parser, ok := gobatis.FindDynamicSqlParser(sqlId)
+		if !ok {
+			if parser, ok = gobatis.FindTemplateSqlParser(sqlId); !ok {
+				return ""
+			}
+		}
+		md, err := parser.ParseMetadata("mysql", map[string]interface{}{"id": 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return md.PrepareSql
+	}
+
+	write(xmlFile, `<mapper namespace="watch"><select id="select">SELECT 1 FROM t</select></mapper>`)
+	write(tplFile, `{{define "namespace"}}watchTpl{{end}}{{define "select"}}SELECT 1 FROM t{{end}}`)
+	if err := gobatis.ScanMapperFile(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gobatis.NewConfiguration().WatchMapperFile(time.Hour); err != gobatiserrors.WatchDirNotFound {
+		t.Fatalf("expect watch dir not found, get %v", err)
+	}
+	if _, err := gobatis.WatchMapperFile(0); err != gobatiserrors.WatchIntervalInvalid {
+		t.Fatalf("expect interval invalid, get %v", err)
+	}
+	w, err := gobatis.WatchMapperFile(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	write(xmlFile, `<mapper namespace="watch">
+    <select id="select">SELECT 2 FROM t WHERE id = #{id}</select>
+    <select id="added">SELECT 3 FROM t</select>
+</mapper>`)
+	write(tplFile, `{{define "namespace"}}watchTpl{{end}}{{define "select"}}SELECT 2 FROM t{{end}}`)
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if prepareSql("watch.select") != "SELECT 2 FROM t WHERE id = ?" || prepareSql("watch.added") != "SELECT 3 FROM t" {
+		t.Fatalf("expect xml reloaded, get %s", prepareSql("watch.select"))
+	}
+	if prepareSql("watchTpl.select") != "SELECT 2 FROM t" {
+		t.Fatalf("expect template reloaded, get %s", prepareSql("watchTpl.select"))
+	}
+
+	//解析失败时保留之前的版本
+	write(xmlFile, `<mapper namespace="watch"><select id="select">SELECT #{} FROM t</select></mapper>`)
+	if err := w.Reload(); err == nil {
+		t.Fatal("expect reload error")
+	}
+	if prepareSql("watch.select") != "SELECT 2 FROM t WHERE id = ?" || prepareSql("watch.added") == "" {
+		t.Fatalf("expect old version kept, get %s", prepareSql("watch.select"))
+	}
+
+	write(xmlFile, `<mapper namespace="watch"><select id="select">SELECT 4 FROM t</select></mapper>`)
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if prepareSql("watch.select") != "SELECT 4 FROM t" || prepareSql("watch.added") != "" {
+		t.Fatalf("expect removed statement unregistered, get %s", prepareSql("watch.select"))
+	}
+}
+
+func TestWatchMapperFilePath(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "watch_path.xml")
+	if err := os.WriteFile(file, []byte(`<mapper namespace="watchPath"><select id="select">SELECT 1 FROM t</select></mapper>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(wd, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := gobatis.NewConfiguration()
+	//注册及检查时使用不同写法的路径
+	if err := config.RegisterMapperFile("./" + rel); err != nil {
+		t.Fatal(err)
+	}
+	w, err := config.WatchMapperFile(time.Hour, dir+string(filepath.Separator)+".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	//文件大小不同，修改时间精度较低时也能检查到修改
+	if err := os.WriteFile(file, []byte(`<mapper namespace="watchPath"><select id="select">SELECT 22 FROM t</select></mapper>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	parser, ok := config.FindSqlParser("watchPath.select")
+	if !ok {
+		t.Fatal("expect statement registered")
+	}
+	md, err := parser.ParseMetadata("mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.PrepareSql != "SELECT 22 FROM t" {
+		t.Fatalf("expect reloaded, get %s", md.PrepareSql)
+	}
+}
@@ -18,7 +18,10 @@
 package test
 
 import (
+	"errors"
+	"fmt"
 	"github.com/acmestack/gobatis"
+	gobatiserrors "github.com/acmestack/gobatis/errors"
 	"github.com/acmestack/gobatis/logging"
 	"github.com/acmestack/gobatis/parsing/xml"
 	"strings"
@@ -37,6 +40,22 @@ func TestXml(t *testing.T) {
 	t.Log(m)
 }
 
+func TestXmlResultMapResultId(t *testing.T) {
+	m, err := xml.Parse([]byte(`<mapper namespace="resultId">
+    <resultMap id="result" type="TestTable">
+        <id property="Id" column="id"/>
+        <result property="Username" column="username"/>
+    </resultMap>
+</mapper>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rm := m.ResultMaps[0]
+	if len(rm.ResultIds) != 1 || rm.ResultId.Column != "id" || rm.ResultId.Property != "Id" {
+		t.Fatalf("expect ResultId kept for compatibility, get %v", rm)
+	}
+}
+
 func TestXmlFormat(t *testing.T) {
 	//xmlFile := os.Getenv("xmlFile")
 	//m, err := xml.ParseFile(xmlFile)
@@ -47,7 +66,7 @@ func TestXmlFormat(t *testing.T) {
 
 	ret := m.Format()
 	for k, v := range ret {
-		t.Logf("id : %s, v : %s\n", k, v)
+		t.Logf("id : %s, v : %v\n", k, v)
 	}
 }
 
@@ -494,3 +513,442 @@ func TestXmlDynamicIfForeach(t *testing.T) {
 
 	})
 }
+
+func TestXmlDynamicTrim(t *testing.T) {
+	src := `INSERT INTO PERSON(id, first, second) VALUES(#{id}, #{first}, #{second})
+        <trim prefix="ON DUPLICATE KEY UPDATE" suffixOverrides=",">
+            <if test="{first} != nil">first = VALUES(first),</if>
+            <if test="{second} != nil">second = VALUES(second),</if>
+        </trim>`
+	m, err := xml.ParseDynamic(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("trim suffix", func(t *testing.T) {
+		ret := m.Replace(map[string]interface{}{"first": "first", "second": "second"})
+		t.Logf("arg both : %s\n", ret)
+		if !strings.HasSuffix(ret, "ON DUPLICATE KEY UPDATE first = VALUES(first), second = VALUES(second)") {
+			t.FailNow()
+		}
+	})
+
+	t.Run("trim empty", func(t *testing.T) {
+		ret := m.Replace(map[string]interface{}{"id": 1})
+		t.Logf("arg none : %s\n", ret)
+		if strings.Index(ret, "ON DUPLICATE") != -1 {
+			t.FailNow()
+		}
+	})
+
+	src = `SELECT * FROM PERSON
+        <trim prefix="WHERE (" suffix=")" prefixOverrides="AND |OR ">
+            <if test="{first} != nil">AND first = #{first}</if>
+            OR second = 1
+        </trim>`
+	m, err = xml.ParseDynamic(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("trim prefix", func(t *testing.T) {
+		ret := m.Replace(map[string]interface{}{"first": "first"})
+		t.Logf("arg first : %s\n", ret)
+		if !strings.HasSuffix(ret, "WHERE ( first = #{first} OR second = 1 )") {
+			t.FailNow()
+		}
+		ret = m.Replace(map[string]interface{}{"id": 1})
+		t.Logf("arg none : %s\n", ret)
+		if !strings.HasSuffix(ret, "WHERE ( second = 1 )") {
+			t.FailNow()
+		}
+	})
+}
+
+func TestXmlDynamicWhereSet(t *testing.T) {
+	src := `UPDATE PERSON
+        <set>
+            <if test="{first} != nil">first = #{first},</if>
+            <if test="{second} != nil">second = #{second}</if>
+        </set>
+        <where>
+            <if test="{id} != nil">AND id = #{id}</if>
+            <if test="{name} != nil">OR name = #{name}</if>
+        </where>`
+	m, err := xml.ParseDynamic(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := m.Replace(map[string]interface{}{"first": "first", "second": "second", "name": "n"})
+	t.Logf("arg : %s\n", ret)
+	if strings.Index(ret, "set first = #{first}, second = #{second}") == -1 {
+		t.Fatal("expect set")
+	}
+	if !strings.HasSuffix(ret, "where name = #{name}") {
+		t.Fatal("expect where")
+	}
+	ret = m.Replace(map[string]interface{}{"first": "first"})
+	t.Logf("arg first : %s\n", ret)
+	if strings.Index(ret, "where") != -1 || strings.Index(ret, "set first = #{first}") == -1 {
+		t.Fatal("expect where removed")
+	}
+}
+
+func TestXmlDynamicBind(t *testing.T) {
+	src := `SELECT * FROM PERSON
+        <bind name="pattern" value="'%' + {name} + '%'"/>
+        <where>
+            <if test="{pattern} != nil">AND name LIKE #{pattern}</if>
+        </where>`
+	m, err := xml.ParseDynamic(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := m.ParseMetadata("mysql", map[string]interface{}{"name": "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("metadata : %s\n", md)
+	if !strings.HasSuffix(md.PrepareSql, "where name LIKE ?") {
+		t.Fatal("expect bind var in sql")
+	}
+	if len(md.Params) != 1 || md.Params[0] != "%abc%" {
+		t.Fatalf("expect bind param, get %v", md.Params)
+	}
+}
+
+func TestXmlDynamicBindScope(t *testing.T) {
+	src := `SELECT * FROM PERSON WHERE
+        <foreach item="name" collection="{names}" open="(" separator=" OR " close=")">
+            <bind name="pattern" value="'%' + {name} + '%'"/>
+            name LIKE #{pattern}
+        </foreach>
+        <if test="{age} != nil">
+            <bind name="minAge" value="{age} - 1"/>
+            AND age > #{minAge}
+        </if>
+        <if test="{minAge} != nil">AND unexpected</if>`
+	m, err := xml.ParseDynamic(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := m.ParseMetadata("mysql", map[string]interface{}{"names": []string{"a", "b"}, "age": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("metadata : %s\n", md)
+	if md.PrepareSql != "SELECT * FROM PERSON WHERE (name LIKE ? OR name LIKE ?)" {
+		t.Fatalf("expect bind in if skipped, get: %s", md.PrepareSql)
+	}
+	if len(md.Params) != 2 || md.Params[0] != "%a%" || md.Params[1] != "%b%" {
+		t.Fatalf("expect bind evaluated for each item, get %v", md.Params)
+	}
+
+	md, err = m.ParseMetadata("mysql", map[string]interface{}{"names": []string{"a"}, "age": 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(md.PrepareSql, "AND age > ? AND unexpected") || len(md.Params) != 2 || md.Params[1] != int64(19) {
+		t.Fatalf("expect bind in if, get: %s %v", md.PrepareSql, md.Params)
+	}
+}
+
+type testExprParam struct {
+	Name  string   `column:"name"`
+	Age   int      `column:"age"`
+	Roles []string `column:"roles"`
+}
+
+func TestXmlDynamicIfExpr(t *testing.T) {
+	param := testExprParam{Name: " Alice ", Age: 20, Roles: []string{"admin", "user"}}
+	gobatis.RegisterModel(&param)
+	cases := []struct {
+		test   string
+		expect bool
+	}{
+		{"{testExprParam.name} != nil and ({testExprParam.age} > 18 or {testExprParam.age} == 0)", true},
+		{"testExprParam.age >= 20 and testExprParam.age lt 21", true},
+		{"testExprParam.age > 3 * 7", false},
+		{"!(testExprParam.age == 20)", false},
+		{"testExprParam.age == '20'", true},
+		{"testExprParam.missing == nil", true},
+		{"not empty(testExprParam.roles) and testExprParam.roles.size() == 2", true},
+		{"contains(testExprParam.roles, 'admin') and testExprParam.roles[1] == 'user'", true},
+		{"testExprParam.name.trim() == 'Alice' and testExprParam.name.trim().length() == 5", true},
+		{"testExprParam.name.trim().startsWith('Al') and upper(testExprParam.name) != testExprParam.name", true},
+		{"{testExprParam.name} == nil or {testExprParam.age} != 20", false},
+		{"testExprParam.age", true},
+		{"testExprParam.age - 20", false},
+		{"testExprParam.age >", false},
+	}
+	for _, c := range cases {
+		src := `SELECT * FROM PERSON <where><if test="` + c.test + `">name = #{testExprParam.name}</if></where>`
+		m, err := xml.ParseDynamic(src, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret := m.Replace(param)
+		if strings.Contains(ret, "where name") != c.expect {
+			t.Fatalf("test [%s] expect %v, get sql: %s", c.test, c.expect, ret)
+		}
+	}
+}
+
+func TestXmlDynamicNested(t *testing.T) {
+	src := `UPDATE PERSON
+        <set>
+            <if test="{name} != nil">
+                name = #{name},
+                <if test="{age} &gt; 0">age = #{age},</if>
+            </if>
+            <choose>
+                <when test="{age} &gt;= 18 &amp;&amp; {age} &lt; 60">level = 'adult'</when>
+                <otherwise>
+                    <trim prefix="level = CASE" suffix="END"><if test="{age} &lt; 18">WHEN 1=1 THEN 'minor'</if></trim>
+                </otherwise>
+            </choose>
+        </set>
+        <where>
+            <if test="{id} != nil">
+                <choose>
+                    <when test="{id} == 0">id IS NULL</when>
+                    <otherwise>id = #{id}</otherwise>
+                </choose>
+            </if>
+            <![CDATA[ AND age < 100 ]]>
+        </where>`
+	m, err := xml.ParseDynamic(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := m.Replace(map[string]interface{}{"name": "a", "age": 20, "id": 1})
+	t.Logf("arg adult : %s\n", ret)
+	if ret != "UPDATE PERSON set name = #{name}, age = #{age}, level = 'adult' where id = #{id} AND age < 100" {
+		t.Fatalf("unexpected sql: %s", ret)
+	}
+	ret = m.Replace(map[string]interface{}{"age": 10})
+	t.Logf("arg minor : %s\n", ret)
+	if ret != "UPDATE PERSON set level = CASE WHEN 1=1 THEN 'minor' END where age < 100" {
+		t.Fatalf("unexpected sql: %s", ret)
+	}
+
+	m, err = xml.ParseDynamic(`UPDATE PERSON
+        <set>
+            status = <choose><when test="{age} &gt;= 18">'A'</when><otherwise>'M'</otherwise></choose>,
+            <if test="{name} != nil">name = #{name}</if>
+        </set>`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret = m.Replace(map[string]interface{}{"age": 20, "name": "a"})
+	t.Logf("arg mixed : %s\n", ret)
+	if ret != "UPDATE PERSON set status = 'A' , name = #{name}" {
+		t.Fatalf("unexpected sql: %s", ret)
+	}
+	ret = m.Replace(map[string]interface{}{"age": 10})
+	if ret != "UPDATE PERSON set status = 'M'" {
+		t.Fatalf("unexpected sql: %s", ret)
+	}
+
+	_, err = xml.ParseDynamic(`SELECT * FROM PERSON <where><if test="{id} != nil">id = #{id}</where>`, nil)
+	if err == nil {
+		t.Fatal("expect error with unclosed element")
+	}
+}
+
+type testForeachRole struct {
+	Name  string   `column:"name"`
+	Perms []string `column:"perms"`
+}
+
+type testForeachUser struct {
+	Id    int               `column:"id"`
+	Roles []testForeachRole `column:"roles"`
+}
+
+func TestXmlDynamicForeachCollections(t *testing.T) {
+	user := testForeachUser{
+		Id: 1,
+		Roles: []testForeachRole{
+			{Name: "admin", Perms: []string{"read", "write"}},
+			{Name: "guest", Perms: []string{"read"}},
+		},
+	}
+	gobatis.RegisterModel(&user)
+	t.Run("nested slice", func(t *testing.T) {
+		src := `INSERT INTO ROLE_PERM(user_id, role, perm) VALUES
+            <foreach item="role" index="i" collection="{testForeachUser.roles}" separator=",">
+                <foreach item="perm" collection="role.perms" separator=",">
+                    (#{testForeachUser.id}, #{role.name}, #{perm})
+                </foreach>
+            </foreach>`
+		m, err := xml.ParseDynamic(src, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		md, err := m.ParseMetadata("mysql", user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("metadata : %s\n", md)
+		if !strings.HasSuffix(md.PrepareSql, "VALUES (?, ?, ?),(?, ?, ?),(?, ?, ?)") {
+			t.Fatalf("unexpected sql: %s", md.PrepareSql)
+		}
+		expect := []interface{}{1, "admin", "read", 1, "admin", "write", 1, "guest", "read"}
+		if fmt.Sprint(md.Params) != fmt.Sprint(expect) {
+			t.Fatalf("expect params %v, get %v", expect, md.Params)
+		}
+	})
+	t.Run("map", func(t *testing.T) {
+		src := `UPDATE CONFIG SET
+            <foreach item="v" index="k" collection="values" separator=",">
+                <if test="v != nil">${k} = #{v}</if>
+            </foreach>`
+		m, err := xml.ParseDynamic(src, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		md, err := m.ParseMetadata("mysql", map[string]interface{}{
+			"values": map[string]int{"b": 2, "a": 1},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("metadata : %s\n", md)
+		if !strings.HasSuffix(md.PrepareSql, "SET a = ?,b = ?") || fmt.Sprint(md.Params) != "[1 2]" {
+			t.Fatalf("unexpected metadata: %s", md)
+		}
+	})
+	t.Run("array", func(t *testing.T) {
+		src := `SELECT * FROM PERSON WHERE id IN
+            <foreach item="id" collection="ids" open="(" separator="," close=")">#{id}</foreach>`
+		m, err := xml.ParseDynamic(src, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		md, err := m.ParseMetadata("mysql", map[string]interface{}{"ids": [3]int64{7, 8, 9}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("metadata : %s\n", md)
+		if !strings.HasSuffix(md.PrepareSql, "id IN (?,?,?)") || fmt.Sprint(md.Params) != "[7 8 9]" {
+			t.Fatalf("unexpected metadata: %s", md)
+		}
+	})
+}
+
+func TestXmlIncludeProperty(t *testing.T) {
+	common := `<mapper namespace="includeCommon">
+    <sql id="columns">${alias}.id, ${alias}.name</sql>
+    <sql id="byId">
+        <where>
+            <if test="{id} != nil">${alias}.id = #{id}</if>
+        </where>
+    </sql>
+    <sql id="loopA"><include refid="loopB"/></sql>
+    <sql id="loopB"><include refid="loopA"/></sql>
+    <sql id="byName">
+        <bind name="pattern" value="'%' + {name} + '%'"/>
+        <where>
+            <if test="{pattern} != nil">name LIKE #{pattern}</if>
+        </where>
+    </sql>
+</mapper>`
+	person := `<mapper namespace="includePerson">
+    <sql id="from">FROM ${table} <include refid="includeCommon.byId"><property name="alias" value="${alias}"/></include></sql>
+    <select id="select">
+        SELECT <include refid="includeCommon.columns"><property name="alias" value="p"/></include>
+        <include refid="from">
+            <property name="table" value="PERSON p"/>
+            <property name="alias" value="p"/>
+        </include>
+    </select>
+    <select id="loop">SELECT <include refid="includeCommon.loopA"/> FROM PERSON</select>
+    <select id="search">SELECT * FROM PERSON <include refid="includeCommon.byName"/></select>
+</mapper>`
+	mgr := xml.NewManager()
+	//引用的片段在使用时查找，注册顺序不影响
+	if err := mgr.RegisterData([]byte(person)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mgr.RegisterData([]byte(common)); err != nil {
+		t.Fatal(err)
+	}
+
+	parser, ok := mgr.FindSqlParser("includePerson.select")
+	if !ok {
+		t.Fatal("sql not found")
+	}
+	md, err := parser.ParseMetadata("mysql", map[string]interface{}{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("metadata : %s\n", md)
+	if md.PrepareSql != "SELECT p.id, p.name FROM PERSON p where p.id = ?" {
+		t.Fatalf("unexpected sql: %s", md.PrepareSql)
+	}
+
+	parser, _ = mgr.FindSqlParser("includePerson.loop")
+	md, err = parser.ParseMetadata("mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.PrepareSql != "SELECT FROM PERSON" {
+		t.Fatalf("expect circular include ignored, get: %s", md.PrepareSql)
+	}
+
+	parser, _ = mgr.FindSqlParser("includePerson.search")
+	md, err = parser.ParseMetadata("mysql", map[string]interface{}{"name": "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.PrepareSql != "SELECT * FROM PERSON where name LIKE ?" || len(md.Params) != 1 || md.Params[0] != "%abc%" {
+		t.Fatalf("expect bind in include, get: %s %v", md.PrepareSql, md.Params)
+	}
+}
+
+func TestXmlMapperValidate(t *testing.T) {
+	data := `<mapper namespace="validate">
+    <resultMap id="result"><association property="Author" resultMap="author"/></resultMap>
+    <select id="select" resultMap="missing">
+        SELECT * FROM t WHERE id = #{ id }
+        <if test="{name} ==">AND name = #{name}</if>
+        <include refid="columns"/>
+        <foo/>
+    </select>
+    <delete id="select">DELETE FROM t WHERE id = #{id}</delete>
+</mapper>`
+	mgr := xml.NewManager()
+	err := mgr.RegisterData([]byte(data))
+	if !errors.Is(err, gobatiserrors.MapperValidateError) {
+		t.Fatalf("expect validate error, get %v", err)
+	}
+	t.Log(err)
+	var verr *xml.ValidateError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expect *xml.ValidateError, get %T", err)
+	}
+	expect := []string{
+		"2: [result] resultMap author not found",
+		"3: [select] resultMap missing not found",
+		"4: [select] parameter #{ id } can never be bound",
+		"5: [select] <if> test [{name} ==] invalid",
+		"6: [select] include refid columns not found",
+		"7: [select] unknown element <foo>",
+		"9: [select] duplicate statement id, first defined at line 3",
+	}
+	if len(verr.Problems) != len(expect) {
+		t.Fatalf("expect %d problems, get %v", len(expect), verr.Problems)
+	}
+	for i, p := range verr.Problems {
+		if !strings.HasPrefix(p.String(), expect[i]) {
+			t.Fatalf("expect %s, get %s", expect[i], p)
+		}
+	}
+	if _, ok := mgr.FindSqlParser("validate.select"); ok {
+		t.Fatal("expect invalid mapper not registered")
+	}
+
+	err = mgr.RegisterData([]byte(`<mapper namespace="validate"><select id="a">SELECT 1</select>`))
+	if !errors.As(err, &verr) || verr.Problems[0].Line != 1 {
+		t.Fatalf("expect syntax error with line, get %v", err)
+	}
+}
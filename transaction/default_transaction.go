@@ -53,8 +53,15 @@ func (trans *DefaultTransaction) Close() {
 
 }
 
-func (trans *DefaultTransaction) Begin() error {
-	tx, err := trans.db.Begin()
+func (trans *DefaultTransaction) Begin(ctx context.Context, opts *sql.TxOptions) error {
+	if trans.tx != nil {
+		return errors.TransactionAlreadyBegin
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tx, err := trans.db.BeginTx(ctx, opts)
 	if err != nil {
 		return err
 	}
@@ -68,6 +75,7 @@ func (trans *DefaultTransaction) Commit() error {
 	}
 
 	err := trans.tx.Commit()
+	trans.tx = nil
 	if err != nil {
 		return errors.TransactionCommitError
 	}
@@ -80,27 +88,60 @@ func (trans *DefaultTransaction) Rollback() error {
 	}
 
 	err := trans.tx.Rollback()
+	trans.tx = nil
 	if err != nil {
 		return errors.TransactionCommitError
 	}
 	return nil
 }
 
-type TransactionConnection struct {
-	tx *sql.Tx
+func (trans *DefaultTransaction) Savepoint(name string) error {
+	return trans.execSavepoint(name, trans.dialect().savepointSql)
+}
+
+func (trans *DefaultTransaction) RollbackTo(name string) error {
+	return trans.execSavepoint(name, trans.dialect().rollbackToSql)
+}
+
+func (trans *DefaultTransaction) Release(name string) error {
+	return trans.execSavepoint(name, trans.dialect().releaseSql)
+}
+
+func (trans *DefaultTransaction) dialect() SavepointDialect {
+	if trans.ds == nil {
+		return standardSavepoint
+	}
+	return SelectSavepointDialect(trans.ds.DriverName())
+}
+
+func (trans *DefaultTransaction) execSavepoint(name string, sqlFunc func(name string) string) error {
+	if trans.tx == nil {
+		return errors.TransactionWithoutBegin
+	}
+
+	if !validSavepointName(name) {
+		return errors.SavepointNameInvalid
+	}
+
+	sqlStr := sqlFunc(name)
+	if sqlStr == "" {
+		return nil
+	}
+	_, err := trans.tx.Exec(sqlStr)
+	return err
 }
 
-type TransactionStatement struct {
-	tx  *sql.Tx
-	sql string
+type TransactionConnection struct {
+	tx *sql.Tx
 }
 
+// Prepare 预编译的语句在事务提交或回滚时自动关闭
 func (transConnection *TransactionConnection) Prepare(sqlStr string) (statement.Statement, error) {
-	ret := &TransactionStatement{
-		tx:  transConnection.tx,
-		sql: sqlStr,
+	s, err := transConnection.tx.Prepare(sqlStr)
+	if err != nil {
+		return nil, err
 	}
-	return ret, nil
+	return (*connection.DefaultStatement)(s), nil
 }
 
 func (transConnection *TransactionConnection) Query(ctx context.Context, result reflection.Object, sqlStr string, params ...interface{}) error {
@@ -112,29 +153,11 @@ func (transConnection *TransactionConnection) Query(ctx context.Context, result
 	defer rows.Close()
 
 	util.ScanRows(rows, result)
-	return nil
+	//读取中断（如超时）时返回错误
+	return rows.Err()
 }
 
 func (transConnection *TransactionConnection) Exec(ctx context.Context, sqlStr string, params ...interface{}) (common.Result, error) {
 	db := transConnection.tx
 	return db.ExecContext(ctx, sqlStr, params...)
 }
-
-func (transStatement *TransactionStatement) Query(ctx context.Context, result reflection.Object, params ...interface{}) error {
-	rows, err := transStatement.tx.QueryContext(ctx, transStatement.sql, params...)
-	if err != nil {
-		return errors.StatementQueryError
-	}
-	defer rows.Close()
-
-	util.ScanRows(rows, result)
-	return nil
-}
-
-func (transStatement *TransactionStatement) Exec(ctx context.Context, params ...interface{}) (common.Result, error) {
-	return transStatement.tx.ExecContext(ctx, transStatement.sql, params...)
-}
-
-func (transStatement *TransactionStatement) Close() {
-	//Will be closed when commit or rollback
-}
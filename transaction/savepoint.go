@@ -0,0 +1,98 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transaction
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// SavepointDialect 保存点语句格式，%s为保存点名称
+// Release为空表示数据库不支持释放保存点，Release时将忽略
+type SavepointDialect struct {
+	Savepoint  string
+	RollbackTo string
+	Release    string
+}
+
+var standardSavepoint = SavepointDialect{
+	Savepoint:  "SAVEPOINT %s",
+	RollbackTo: "ROLLBACK TO SAVEPOINT %s",
+	Release:    "RELEASE SAVEPOINT %s",
+}
+
+var gSavepointMap = map[string]SavepointDialect{
+	"mysql":    standardSavepoint, //mysql
+	"postgres": standardSavepoint, //postgresql
+	"sqlite3":  standardSavepoint, //sqlite
+	"oci8": { //oracle
+		Savepoint:  "SAVEPOINT %s",
+		RollbackTo: "ROLLBACK TO SAVEPOINT %s",
+	},
+	"adodb": { //sqlserver
+		Savepoint:  "SAVE TRANSACTION %s",
+		RollbackTo: "ROLLBACK TRANSACTION %s",
+	},
+}
+
+// RegisterSavepointDialect 注册数据库驱动的保存点语句，返回是否覆盖了已有的注册
+func RegisterSavepointDialect(driverName string, dialect SavepointDialect) bool {
+	_, ok := gSavepointMap[driverName]
+	gSavepointMap[driverName] = dialect
+	return ok
+}
+
+// SelectSavepointDialect 获得数据库驱动的保存点语句，未注册的驱动使用标准SQL语句
+func SelectSavepointDialect(driverName string) SavepointDialect {
+	if v, ok := gSavepointMap[driverName]; ok {
+		return v
+	}
+	return standardSavepoint
+}
+
+func (dialect SavepointDialect) savepointSql(name string) string {
+	return fmt.Sprintf(dialect.Savepoint, name)
+}
+
+func (dialect SavepointDialect) rollbackToSql(name string) string {
+	return fmt.Sprintf(dialect.RollbackTo, name)
+}
+
+func (dialect SavepointDialect) releaseSql(name string) string {
+	if dialect.Release == "" {
+		return ""
+	}
+	return fmt.Sprintf(dialect.Release, name)
+}
+
+// validSavepointName 保存点名称直接拼接到语句中，只允许字母、数字和下划线
+func validSavepointName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r == '_' || unicode.IsLetter(r) {
+			continue
+		}
+		if i > 0 && unicode.IsDigit(r) {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -18,6 +18,9 @@
 package transaction
 
 import (
+	"context"
+	"database/sql"
+
 	"github.com/acmestack/gobatis/connection"
 )
 
@@ -26,9 +29,20 @@ type Transaction interface {
 
 	GetConnection() connection.Connection
 
-	Begin() error
+	// Begin 开启事务，ctx在事务提交或回滚前有效，ctx取消时事务将被回滚
+	// opts为nil时使用数据库默认的事务选项
+	Begin(ctx context.Context, opts *sql.TxOptions) error
 
 	Commit() error
 
 	Rollback() error
+
+	// Savepoint 在当前事务中创建保存点
+	Savepoint(name string) error
+
+	// RollbackTo 回滚到保存点，保存点之前的操作不受影响
+	RollbackTo(name string) error
+
+	// Release 释放保存点
+	Release(name string) error
 }
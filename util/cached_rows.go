@@ -0,0 +1,181 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"reflect"
+
+	"github.com/acmestack/gobatis/reflection"
+)
+
+// CachedRows 缓存的查询结果，可多次反序列化到不同的对象中
+type CachedRows struct {
+	columns []string
+	values  [][]interface{}
+}
+
+// Len 结果行数
+func (rows *CachedRows) Len() int {
+	return len(rows.values)
+}
+
+// Scan 将缓存的结果反序列化到result中，返回读取的行数
+func (rows *CachedRows) Scan(result reflection.Object) int64 {
+	var index int64 = 0
+	values := make([]interface{}, len(rows.columns))
+	for _, row := range rows.values {
+		for i, v := range row {
+			//复制[]byte，避免结果修改缓存
+			if b, ok := v.([]byte); ok {
+				v = append([]byte(nil), b...)
+			}
+			values[i] = v
+		}
+		if !deserialize(result, rows.columns, values) {
+			break
+		}
+		index++
+	}
+	return index
+}
+
+// RowsRecorder 记录查询结果的Object，用于缓存查询结果
+type RowsRecorder struct {
+	rows    *CachedRows
+	current *rowRecorder
+}
+
+func NewRowsRecorder() *RowsRecorder {
+	return &RowsRecorder{rows: &CachedRows{}}
+}
+
+// Rows 获得记录的查询结果
+func (recorder *RowsRecorder) Rows() *CachedRows {
+	return recorder.rows
+}
+
+func (recorder *RowsRecorder) Kind() int {
+	return reflection.ObjectCustom
+}
+
+func (recorder *RowsRecorder) New() reflection.Object {
+	return NewRowsRecorder()
+}
+
+func (recorder *RowsRecorder) NewElem() reflection.Object {
+	recorder.current = &rowRecorder{recorder: recorder}
+	return recorder.current
+}
+
+func (recorder *RowsRecorder) SetField(name string, v interface{}) {
+}
+
+func (recorder *RowsRecorder) AddValue(v reflect.Value) {
+	if recorder.current != nil {
+		recorder.rows.values = append(recorder.rows.values, recorder.current.values)
+		recorder.current = nil
+	}
+}
+
+func (recorder *RowsRecorder) GetClassName() string {
+	return "RowsRecorder"
+}
+
+func (recorder *RowsRecorder) CanSetField() bool {
+	return false
+}
+
+func (recorder *RowsRecorder) CanAddValue() bool {
+	return true
+}
+
+func (recorder *RowsRecorder) NewValue() reflect.Value {
+	return reflect.Value{}
+}
+
+func (recorder *RowsRecorder) CanSet(v reflect.Value) bool {
+	return false
+}
+
+func (recorder *RowsRecorder) SetValue(v reflect.Value) {
+}
+
+func (recorder *RowsRecorder) GetValue() reflect.Value {
+	return reflect.Value{}
+}
+
+func (recorder *RowsRecorder) ResetValue(v reflect.Value) {
+}
+
+// rowRecorder 记录一行数据，第一行时同时记录列名
+type rowRecorder struct {
+	recorder *RowsRecorder
+	values   []interface{}
+}
+
+func (row *rowRecorder) Kind() int {
+	return reflection.ObjectCustom
+}
+
+func (row *rowRecorder) New() reflection.Object {
+	return &rowRecorder{recorder: row.recorder}
+}
+
+func (row *rowRecorder) NewElem() reflection.Object {
+	return row
+}
+
+func (row *rowRecorder) SetField(name string, v interface{}) {
+	if len(row.recorder.rows.values) == 0 {
+		row.recorder.rows.columns = append(row.recorder.rows.columns, name)
+	}
+	row.values = append(row.values, v)
+}
+
+func (row *rowRecorder) AddValue(v reflect.Value) {
+}
+
+func (row *rowRecorder) GetClassName() string {
+	return "RowRecorder"
+}
+
+func (row *rowRecorder) CanSetField() bool {
+	return true
+}
+
+func (row *rowRecorder) CanAddValue() bool {
+	return false
+}
+
+func (row *rowRecorder) NewValue() reflect.Value {
+	return reflect.Value{}
+}
+
+func (row *rowRecorder) CanSet(v reflect.Value) bool {
+	return false
+}
+
+func (row *rowRecorder) SetValue(v reflect.Value) {
+}
+
+func (row *rowRecorder) GetValue() reflect.Value {
+	return reflect.Value{}
+}
+
+func (row *rowRecorder) ResetValue(v reflect.Value) {
+}
@@ -63,6 +63,10 @@ func deserialize(result reflection.Object, columns []string, values []interface{
 	}
 	if result.CanAddValue() {
 		result.AddValue(obj.GetValue())
+		//迭代对象在回调返回true时停止读取
+		if iterObj, ok := result.(*reflection.IterObject); ok {
+			return !iterObj.Broken()
+		}
 		return true
 	}
 	return false
@@ -0,0 +1,168 @@
+/*
+ * Licensed to the AcmeStack under one or more contributor license
+ * agreements. See the NOTICE file distributed with this work for
+ * additional information regarding copyright ownership.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gobatis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acmestack/gobatis/errors"
+	"github.com/acmestack/gobatis/logging"
+)
+
+// MapperWatcher 定时检查mapper目录，重新加载新增或修改的xml及tpl文件，用于开发环境
+type MapperWatcher struct {
+	config   *Configuration
+	dirs     []string
+	interval time.Duration
+	files    map[string]fileState
+	lock     sync.Mutex
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// ReloadError 重新加载失败的文件及错误，加载失败的文件继续使用之前的版本
+type ReloadError struct {
+	Files  []string
+	Errors []error
+}
+
+func (e *ReloadError) Error() string {
+	ret := strings.Builder{}
+	ret.WriteString("reload mapper failed:")
+	for i := range e.Files {
+		ret.WriteString("\n")
+		ret.WriteString(e.Files[i])
+		ret.WriteString(": ")
+		ret.WriteString(e.Errors[i].Error())
+	}
+	return ret.String()
+}
+
+// WatchMapperFile 每隔interval检查dirs中的mapper文件，dirs为空时检查ScanMapperFile扫描过的目录
+// 修改的文件替换该文件之前注册的语句，解析失败时打印错误并继续使用之前的版本；删除的文件不做处理
+// interval小于等于0或没有需要检查的目录时返回错误，不再使用时需调用Stop停止检查
+func WatchMapperFile(interval time.Duration, dirs ...string) (*MapperWatcher, error) {
+	return defaultConfiguration.WatchMapperFile(interval, dirs...)
+}
+
+func newMapperWatcher(config *Configuration, interval time.Duration, dirs []string) (*MapperWatcher, error) {
+	if interval <= 0 {
+		return nil, errors.WatchIntervalInvalid
+	}
+	w := &MapperWatcher{
+		config:   config,
+		dirs:     dirs,
+		interval: interval,
+		files:    map[string]fileState{},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	w.walk(func(path string, state fileState) {
+		w.files[path] = state
+	})
+	go w.run()
+	return w, nil
+}
+
+func (w *MapperWatcher) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			if err := w.Reload(); err != nil {
+				logging.Warn("%v\n", err)
+			}
+		}
+	}
+}
+
+// Reload 立即检查并重新加载新增或修改的文件，存在加载失败的文件时返回*ReloadError
+func (w *MapperWatcher) Reload() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var ret *ReloadError
+	w.walk(func(path string, state fileState) {
+		if old, ok := w.files[path]; ok && old == state {
+			return
+		}
+		//失败时也记录，文件再次修改后重新加载
+		w.files[path] = state
+		if err := w.config.reloadMapperFile(path); err != nil {
+			if ret == nil {
+				ret = &ReloadError{}
+			}
+			ret.Files = append(ret.Files, path)
+			ret.Errors = append(ret.Errors, err)
+		}
+	})
+	if ret == nil {
+		return nil
+	}
+	return ret
+}
+
+// Stop 停止检查并等待正在进行的加载完成
+func (w *MapperWatcher) Stop() {
+	w.lock.Lock()
+	select {
+	case <-w.stop:
+		w.lock.Unlock()
+		return
+	default:
+		close(w.stop)
+	}
+	w.lock.Unlock()
+	<-w.done
+}
+
+// walk 遍历目录中的xml及tpl文件
+func (w *MapperWatcher) walk(f func(path string, state fileState)) {
+	for _, dir := range w.dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && isMapperFile(path) {
+				f(mapperPath(path), fileState{modTime: info.ModTime(), size: info.Size()})
+			}
+			return nil
+		})
+		if err != nil {
+			logging.Warn("watch mapper dir failed: %s err: %v\n", dir, err)
+		}
+	}
+}
+
+func isMapperFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".xml" || ext == ".tpl"
+}